@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -31,17 +32,33 @@ type App struct {
 	installingGit     bool               // Flag to prevent concurrent Git installation
 	nodeInstallDone   chan bool          // Channel to signal Node.js installation completion
 	installMutex      sync.Mutex
+	promptRuns        map[string]*promptRunState // One-shot prompt runs by id
+	promptRunMutex    sync.Mutex
+	configWriteMutex  sync.Mutex // Serializes writes of tool config files
+	nodeVersionMutex  sync.Mutex // Serializes Node.js version downloads
+	toolVersionCache  map[string]toolVersionEntry // Tool --version results by resolved binary path
+	toolVersionMutex  sync.Mutex
+	pythonEnvCache    map[string]PythonEnvironment // Listed Python environments by project and name
+	pythonEnvMutex    sync.Mutex
 }
 var OnConfigChanged func(AppConfig)
 var UpdateTrayMenu func(string)
 var UpdateTrayVisibility func(bool)
 type ModelConfig struct {
-	ModelName string `json:"model_name"`
-	ModelId   string `json:"model_id"`
-	ModelUrl  string `json:"model_url"`
-	ApiKey    string `json:"api_key"`
-	WireApi   string `json:"wire_api"`
-	IsCustom  bool   `json:"is_custom"`
+	ModelName   string   `json:"model_name"`
+	ModelId     string   `json:"model_id"`
+	ModelUrl    string   `json:"model_url"`
+	ApiKey      string   `json:"api_key"`
+	WireApi     string   `json:"wire_api"`
+	IsCustom    bool     `json:"is_custom"`
+	ExtraArgs   []string `json:"extra_args,omitempty"`   // Extra CLI arguments when this provider is selected
+	ExtraEnv    []EnvVar `json:"extra_env,omitempty"`    // Extra environment variables when this provider is selected
+	ProxyPolicy string   `json:"proxy_policy,omitempty"` // "always", "never" or empty to follow the project's proxy setting
+}
+// EnvVar is a single environment variable. Lists of EnvVar keep the order the user entered.
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
 }
 type ProjectConfig struct {
 	Id            string `json:"id"`
@@ -57,15 +74,45 @@ type ProjectConfig struct {
 	ProxyPort     string `json:"proxy_port"`
 	ProxyUsername string `json:"proxy_username"`
 	ProxyPassword string `json:"proxy_password"`
+	ProxyScheme   string `json:"proxy_scheme,omitempty"`   // "http" (default), "https" or "socks5"
+	ProxyNoProxy  string `json:"proxy_no_proxy,omitempty"` // Hosts that bypass the proxy, comma separated
+	LaunchMode    string `json:"launch_mode"` // "terminal" (default), "tmux" or "zellij"
+	// Extra launch options (project-specific, applied after tool and provider options)
+	ExtraArgs []string `json:"extra_args,omitempty"`
+	ExtraEnv  []EnvVar `json:"extra_env,omitempty"`
+	Hooks     []Hook   `json:"hooks,omitempty"` // Run after the global hooks
+	// Node.js version for tool launches, e.g. "20" or "lts/*" (overrides .nvmrc/.node-version)
+	NodeVersion string `json:"node_version,omitempty"`
+	// Remote host (project-specific); tools run there over SSH when SSHHost is set
+	SSHHost       string `json:"ssh_host,omitempty"`
+	SSHPort       string `json:"ssh_port,omitempty"`
+	SSHUser       string `json:"ssh_user,omitempty"`
+	SSHIdentity   string `json:"ssh_identity,omitempty"`   // Private key file, empty for the ssh defaults
+	RemotePath    string `json:"remote_path,omitempty"`    // Project directory on the remote host, empty for the home directory
+	RemoteInstall bool   `json:"remote_install,omitempty"` // Install the tool on the remote host when missing
+	// Container sandbox for yolo launches (project-specific)
+	ContainerMode    bool   `json:"container_mode,omitempty"`    // Run yolo launches in a container with only the project mounted
+	ContainerRuntime string `json:"container_runtime,omitempty"` // "docker" or "podman", empty to detect
+	ContainerImage   string `json:"container_image,omitempty"`   // Empty for a Node.js LTS image
+	ContainerNetwork string `json:"container_network,omitempty"` // "none" or a network name, empty for the default network
+	// Sandbox for launches on Linux (project-specific)
+	Sandbox SandboxProfile `json:"sandbox"`
 }
 type PythonEnvironment struct {
-	Name string `json:"name"` // Environment name (e.g.", "base", "myenv")
-	Path string `json:"path"` // Full path to the environment
-	Type string `json:"type"` // "conda", "venv", or "system"
+	Name         string `json:"name"`          // Environment name (e.g.", "base", "myenv")
+	Path         string `json:"path"`          // Full path to the environment
+	Type         string `json:"type"`          // "conda", "venv", "uv", "poetry", "pyenv" or "system"
+	Version      string `json:"version"`       // Python version, e.g. "3.12.4"
+	Python       string `json:"python"`        // Interpreter path
+	ProjectLocal bool   `json:"project_local"` // Belongs to the current project
 }
 type ToolConfig struct {
-	CurrentModel string        `json:"current_model"`
-	Models       []ModelConfig `json:"models"`
+	CurrentModel  string        `json:"current_model"`
+	Models        []ModelConfig `json:"models"`
+	ExtraArgs     []string      `json:"extra_args,omitempty"`     // Extra CLI arguments for every launch of this tool
+	ExtraEnv      []EnvVar      `json:"extra_env,omitempty"`      // Extra environment variables for every launch of this tool
+	NodeVersion   string        `json:"node_version,omitempty"`   // Node.js version this tool runs on, empty for the default
+	PinnedVersion string        `json:"pinned_version,omitempty"` // Exact version or npm dist-tag to install instead of latest
 }
 type CodeBuddyModel struct {
 	Id               string `json:"id"`
@@ -92,6 +139,7 @@ type AppConfig struct {
 	IFlow                ToolConfig      `json:"iflow"`
 	Kilo                 ToolConfig      `json:"kilo"`
 	Kode                 ToolConfig      `json:"kode"`
+	Aider                ToolConfig      `json:"aider"`
 	Projects             []ProjectConfig `json:"projects"`
 	CurrentProject       string          `json:"current_project"` // ID of the current project
 	ActiveTool           string          `json:"active_tool"`     // "claude", "gemini", or "codex"
@@ -116,6 +164,23 @@ type AppConfig struct {
 	DefaultProxyPort     string `json:"default_proxy_port"`
 	DefaultProxyUsername string `json:"default_proxy_username"`
 	DefaultProxyPassword string `json:"default_proxy_password"`
+	DefaultProxyScheme   string `json:"default_proxy_scheme,omitempty"`
+	DefaultProxyNoProxy  string `json:"default_proxy_no_proxy,omitempty"`
+	// npm registry for tool installs, updates and version queries
+	NpmRegistry      string `json:"npm_registry,omitempty"`       // "auto" (default), "official", "npmmirror", "custom" or "npmrc"
+	NpmRegistryURL   string `json:"npm_registry_url,omitempty"`   // Registry URL when NpmRegistry is "custom"
+	NpmRegistryToken string `json:"npm_registry_token,omitempty"` // Auth token for the custom registry
+	// Release installers replacing the built-in ones by tool name
+	ToolInstallers []ToolInstaller `json:"tool_installers,omitempty"`
+	// Terminal settings (Linux)
+	TerminalProfile  string `json:"terminal_profile"`  // "auto", a built-in terminal name, or "custom"
+	TerminalTemplate string `json:"terminal_template"` // Custom command template with {script}, {dir} and {title}
+	// Hooks run around every tool launch (global)
+	Hooks []Hook `json:"hooks,omitempty"`
+	// Windows: also store the launched provider's key and URL in the user
+	// environment with setx, for terminals opened outside AICoder. Off by
+	// default, since it leaves the key in the registry.
+	PersistProviderEnv bool `json:"persist_provider_env,omitempty"`
 }
 type Skill struct {
 	Name        string `json:"name"`
@@ -129,6 +194,7 @@ func NewApp() *App {
 	return &App{
 		downloadCancelers: make(map[string]context.CancelFunc),
 		nodeInstallDone:   make(chan bool, 1), // Buffered channel to signal Node.js installation completion
+		promptRuns:        make(map[string]*promptRunState),
 	}
 }
 // startup is called when the app starts. The context is saved
@@ -291,34 +357,29 @@ func (a *App) getIFlowConfigPaths() (string, string) {
 	config := filepath.Join(dir, "settings.json")
 	return dir, config
 }
-func (a *App) clearClaudeConfig() {
+func (a *App) clearClaudeConfig(files *configFileSet) {
 	dir, _, legacy := a.getClaudeConfigPaths()
 	home, _ := os.UserHomeDir()
-	os.RemoveAll(dir)
-	os.Remove(legacy)
-	os.Remove(filepath.Join(home, ".claude.json.backup"))
-	a.log("Cleared Claude configuration files")
+	files.removeAll(dir)
+	files.remove(legacy)
+	files.remove(filepath.Join(home, ".claude.json.backup"))
 }
-func (a *App) clearGeminiConfig() {
+func (a *App) clearGeminiConfig(files *configFileSet) {
 	dir, _, legacy := a.getGeminiConfigPaths()
-	os.RemoveAll(dir)
-	os.Remove(legacy)
-	a.log("Cleared Gemini configuration files")
+	files.removeAll(dir)
+	files.remove(legacy)
 }
-func (a *App) clearCodexConfig() {
+func (a *App) clearCodexConfig(files *configFileSet) {
 	dir, _ := a.getCodexConfigPaths()
-	os.RemoveAll(dir)
-	a.log("Cleared Codex configuration directory")
+	files.removeAll(dir)
 }
-func (a *App) clearOpencodeConfig() {
+func (a *App) clearOpencodeConfig(files *configFileSet) {
 	dir, _ := a.getOpencodeConfigPaths()
-	os.RemoveAll(dir)
-	a.log("Cleared Opencode configuration directory")
+	files.removeAll(dir)
 }
-func (a *App) clearIFlowConfig() {
+func (a *App) clearIFlowConfig(files *configFileSet) {
 	dir, _ := a.getIFlowConfigPaths()
-	os.RemoveAll(dir)
-	a.log("Cleared iFlow configuration directory")
+	files.removeAll(dir)
 }
 func (a *App) getKiloConfigPaths() (string, string) {
 	home, _ := os.UserHomeDir()
@@ -326,10 +387,9 @@ func (a *App) getKiloConfigPaths() (string, string) {
 	config := filepath.Join(dir, "config.json")
 	return dir, config
 }
-func (a *App) clearKiloConfig() {
+func (a *App) clearKiloConfig(files *configFileSet) {
 	_, configPath := a.getKiloConfigPaths()
-	os.Remove(configPath)
-	a.log("Cleared Kilo Code configuration file")
+	files.remove(configPath)
 }
 func (a *App) getKodeConfigPaths() (string, string) {
 	home, _ := os.UserHomeDir()
@@ -337,27 +397,26 @@ func (a *App) getKodeConfigPaths() (string, string) {
 	config := filepath.Join(dir, "config.json")
 	return dir, config
 }
-func (a *App) clearKodeConfig() {
+func (a *App) clearKodeConfig(files *configFileSet) {
 	_, configPath := a.getKodeConfigPaths()
-	os.Remove(configPath)
-	a.log("Cleared Kode CLI configuration file")
+	files.remove(configPath)
 }
-func (a *App) clearEnvVars() {
-	vars := []string{
-		"ANTHROPIC_API_KEY", "ANTHROPIC_BASE_URL", "ANTHROPIC_AUTH_TOKEN",
-		"OPENAI_API_KEY", "OPENAI_BASE_URL", "WIRE_API",
-		"GEMINI_API_KEY", "GOOGLE_GEMINI_BASE_URL",
-		"OPENCODE_API_KEY", "OPENCODE_BASE_URL",
-		"CODEBUDDY_API_KEY", "CODEBUDDY_BASE_URL", "CODEBUDDY_CODE_MAX_OUTPUT_TOKENS",
-		"QODER_PERSONAL_ACCESS_TOKEN", "QODER_BASE_URL",
-		"IFLOW_API_KEY", "IFLOW_BASE_URL",
-		"KILO_API_KEY", "KILO_BASE_URL", "KILO_MODEL",
-	}
-	for _, v := range vars {
-		os.Unsetenv(v)
-	}
+// providerEnvVars are the environment variables AICoder sets for providers. A launched
+// tool never inherits them from AICoder's own environment; launch scripts unset
+// the ones the launch plan does not set.
+var providerEnvVars = []string{
+	"ANTHROPIC_API_KEY", "ANTHROPIC_BASE_URL", "ANTHROPIC_AUTH_TOKEN",
+	"OPENAI_API_KEY", "OPENAI_BASE_URL", "WIRE_API",
+	"GEMINI_API_KEY", "GOOGLE_GEMINI_BASE_URL",
+	"OPENCODE_API_KEY", "OPENCODE_BASE_URL",
+	"CODEBUDDY_API_KEY", "CODEBUDDY_BASE_URL", "CODEBUDDY_CODE_MAX_OUTPUT_TOKENS",
+	"QODER_PERSONAL_ACCESS_TOKEN", "QODER_BASE_URL",
+	"IFLOW_API_KEY", "IFLOW_BASE_URL",
+	"KILO_API_KEY", "KILO_BASE_URL", "KILO_MODEL",
+	"ANTHROPIC_MODEL", "GOOGLE_GEMINI_MODEL", "OPENAI_MODEL", "OPENCODE_MODEL", "IFLOW_MODEL", "KODE_MODEL",
+	"OPENAI_API_BASE", "AIDER_MODEL",
 }
-func (a *App) syncToClaudeSettings(config AppConfig) error {
+func (a *App) syncToClaudeSettings(config AppConfig, files *configFileSet) error {
 	var selectedModel *ModelConfig
 	for _, m := range config.Claude.Models {
 		if m.ModelName == config.Claude.CurrentModel {
@@ -368,14 +427,11 @@ func (a *App) syncToClaudeSettings(config AppConfig) error {
 	if selectedModel == nil {
 		return fmt.Errorf("selected model not found")
 	}
-	dir, settingsPath, legacyPath := a.getClaudeConfigPaths()
+	_, settingsPath, legacyPath := a.getClaudeConfigPaths()
 	if strings.ToLower(selectedModel.ModelName) == "original" {
-		a.clearClaudeConfig()
+		a.clearClaudeConfig(files)
 		return nil
 	}
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
 	settings := make(map[string]interface{})
 	env := make(map[string]string)
 	// Exclusively use AUTH_TOKEN for custom providers
@@ -441,19 +497,8 @@ func (a *App) syncToClaudeSettings(config AppConfig) error {
 	if err != nil {
 		return err
 	}
-	// Check if settings file needs update
-	if existingData, err := os.ReadFile(settingsPath); err == nil {
-		if bytes.Equal(existingData, data) {
-			// Settings file is already up to date, skip main settings write
-			// But still need to update .claude.json for API key responses
-			goto updateLegacyJson
-		}
-	}
-	if err := os.WriteFile(settingsPath, data, 0644); err != nil {
-		return err
-	}
+	files.write(settingsPath, data)
 	// 2. Sync to ~/.claude.json for customApiKeyResponses
-updateLegacyJson:
 	var claudeJson map[string]interface{}
 	if jsonData, err := os.ReadFile(legacyPath); err == nil {
 		json.Unmarshal(jsonData, &claudeJson)
@@ -461,23 +506,22 @@ updateLegacyJson:
 	if claudeJson == nil {
 		claudeJson = make(map[string]interface{})
 	}
-	claudeJson["customApiKeyResponses"] = map[string]interface{}{
-		"approved": []string{selectedModel.ApiKey},
-		"rejected": []string{},
+	owned := map[string]interface{}{
+		"customApiKeyResponses": map[string]interface{}{
+			"approved": []string{selectedModel.ApiKey},
+			"rejected": []string{},
+		},
+	}
+	for k, v := range owned {
+		claudeJson[k] = v
 	}
 	data2, err := json.MarshalIndent(claudeJson, "", "  ")
 	if err != nil {
 		return err
 	}
-	// Check if legacy file needs update
-	if existingData, err := os.ReadFile(legacyPath); err == nil {
-		if bytes.Equal(existingData, data2) {
-			return nil
-		}
-	}
-	return os.WriteFile(legacyPath, data2, 0644)
+	return files.merge(legacyPath, data2, owned)
 }
-func (a *App) syncToCodexSettings(config AppConfig) error {
+func (a *App) syncToCodexSettings(config AppConfig, files *configFileSet) error {
 	var selectedModel *ModelConfig
 	for _, m := range config.Codex.Models {
 		if m.ModelName == config.Codex.CurrentModel {
@@ -490,12 +534,9 @@ func (a *App) syncToCodexSettings(config AppConfig) error {
 	}
 	dir, authPath := a.getCodexConfigPaths()
 	if strings.ToLower(selectedModel.ModelName) == "original" {
-		a.clearCodexConfig()
+		a.clearCodexConfig(files)
 		return nil
 	}
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
 	// Create auth.json
 	authData := map[string]string{
 		"OPENAI_API_KEY": selectedModel.ApiKey,
@@ -504,18 +545,8 @@ func (a *App) syncToCodexSettings(config AppConfig) error {
 	if err != nil {
 		return err
 	}
-	// Check if auth.json needs update
-	if existingData, err := os.ReadFile(authPath); err == nil {
-		if bytes.Equal(existingData, authJson) {
-			// Auth file is already up to date, skip writing
-			goto writeConfigToml
-		}
-	}
-	if err := os.WriteFile(authPath, authJson, 0644); err != nil {
-		return err
-	}
+	files.write(authPath, authJson)
 	// Create config.toml
-writeConfigToml:
 	configPath := filepath.Join(dir, "config.toml")
 	baseUrl := selectedModel.ModelUrl
 	var configToml string
@@ -712,16 +743,10 @@ base_url = "%s"
 wire_api = "%s"
 `, providerName, modelId, providerName, providerName, baseUrl, wireApi)
 	}
-	configBytes := []byte(configToml)
-	// Check if config.toml needs update
-	if existingData, err := os.ReadFile(configPath); err == nil {
-		if bytes.Equal(existingData, configBytes) {
-			return nil
-		}
-	}
-	return os.WriteFile(configPath, configBytes, 0644)
+	files.write(configPath, []byte(configToml))
+	return nil
 }
-func (a *App) syncToOpencodeSettings(config AppConfig) error {
+func (a *App) syncToOpencodeSettings(config AppConfig, files *configFileSet) error {
 	var selectedModel *ModelConfig
 	for _, m := range config.Opencode.Models {
 		if m.ModelName == config.Opencode.CurrentModel {
@@ -732,14 +757,11 @@ func (a *App) syncToOpencodeSettings(config AppConfig) error {
 	if selectedModel == nil {
 		return fmt.Errorf("selected opencode model not found")
 	}
-	dir, configPath := a.getOpencodeConfigPaths()
+	_, configPath := a.getOpencodeConfigPaths()
 	if strings.ToLower(selectedModel.ModelName) == "original" {
-		a.clearOpencodeConfig()
+		a.clearOpencodeConfig(files)
 		return nil
 	}
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
 	baseUrl := selectedModel.ModelUrl
 	modelId := selectedModel.ModelId
 	providerName := selectedModel.ModelName
@@ -806,9 +828,10 @@ func (a *App) syncToOpencodeSettings(config AppConfig) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(configPath, data, 0644)
+	files.write(configPath, data)
+	return nil
 }
-func (a *App) syncToGeminiSettings(config AppConfig) error {
+func (a *App) syncToGeminiSettings(config AppConfig, files *configFileSet) error {
 	var selectedModel *ModelConfig
 	for _, m := range config.Gemini.Models {
 		if m.ModelName == config.Gemini.CurrentModel {
@@ -820,11 +843,7 @@ func (a *App) syncToGeminiSettings(config AppConfig) error {
 		return fmt.Errorf("selected gemini model not found")
 	}
 
-	dir, configPath, _ := a.getGeminiConfigPaths()
-
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
+	_, configPath, _ := a.getGeminiConfigPaths()
 
 	var configData map[string]interface{}
 
@@ -862,17 +881,10 @@ func (a *App) syncToGeminiSettings(config AppConfig) error {
 		return err
 	}
 
-	// Check if file exists and has same content to avoid unnecessary writes
-	if existingData, err := os.ReadFile(configPath); err == nil {
-		if bytes.Equal(existingData, configJson) {
-			// File already has the correct content, skip writing
-			return nil
-		}
-	}
-
-	return os.WriteFile(configPath, configJson, 0644)
+	files.write(configPath, configJson)
+	return nil
 }
-func (a *App) syncToIFlowSettings(config AppConfig) error {
+func (a *App) syncToIFlowSettings(config AppConfig, files *configFileSet) error {
 	var selectedModel *ModelConfig
 	for _, m := range config.IFlow.Models {
 		if m.ModelName == config.IFlow.CurrentModel {
@@ -883,14 +895,11 @@ func (a *App) syncToIFlowSettings(config AppConfig) error {
 	if selectedModel == nil {
 		return fmt.Errorf("selected iflow model not found")
 	}
-	dir, configPath := a.getIFlowConfigPaths()
+	_, configPath := a.getIFlowConfigPaths()
 	if strings.ToLower(selectedModel.ModelName) == "original" {
-		a.clearIFlowConfig()
+		a.clearIFlowConfig(files)
 		return nil
 	}
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
 	// Prepare defaults
 	baseUrl := selectedModel.ModelUrl
 	modelId := selectedModel.ModelId
@@ -938,9 +947,10 @@ func (a *App) syncToIFlowSettings(config AppConfig) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(configPath, data, 0644)
+	files.write(configPath, data)
+	return nil
 }
-func (a *App) syncToKiloSettings(config AppConfig) error {
+func (a *App) syncToKiloSettings(config AppConfig, files *configFileSet) error {
 	var selectedModel *ModelConfig
 	for _, m := range config.Kilo.Models {
 		if m.ModelName == config.Kilo.CurrentModel {
@@ -951,14 +961,11 @@ func (a *App) syncToKiloSettings(config AppConfig) error {
 	if selectedModel == nil {
 		return fmt.Errorf("selected kilo model not found")
 	}
-	dir, configPath := a.getKiloConfigPaths()
+	_, configPath := a.getKiloConfigPaths()
 	if strings.ToLower(selectedModel.ModelName) == "original" {
-		a.clearKiloConfig()
+		a.clearKiloConfig(files)
 		return nil
 	}
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
 	// Read existing config if it exists
 	var kiloConfig map[string]interface{}
 	existingData, err := os.ReadFile(configPath)
@@ -1022,16 +1029,17 @@ func (a *App) syncToKiloSettings(config AppConfig) error {
 		"openAiBaseUrl": baseUrl,
 	}
 	// Update providers array
-	kiloConfig["providers"] = []interface{}{provider}
+	owned := map[string]interface{}{"providers": []interface{}{provider}}
+	kiloConfig["providers"] = owned["providers"]
 	// Write config file
 	data, err := json.MarshalIndent(kiloConfig, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(configPath, data, 0644)
+	return files.merge(configPath, data, owned)
 }
 
-func (a *App) syncToKodeSettings(config AppConfig) error {
+func (a *App) syncToKodeSettings(config AppConfig, files *configFileSet) error {
 	// Kode CLI uses .kode.json configuration file in user home directory
 	var selectedModel *ModelConfig
 	for _, m := range config.Kode.Models {
@@ -1084,9 +1092,10 @@ func (a *App) syncToKodeSettings(config AppConfig) error {
 		return err
 	}
 
-	return os.WriteFile(kodeConfigPath, data, 0644)
+	files.write(kodeConfigPath, data)
+	return nil
 }
-func (a *App) syncToCodeBuddySettings(config AppConfig, projectPath string) error {
+func (a *App) syncToCodeBuddySettings(config AppConfig, projectPath string, files *configFileSet) error {
 	if projectPath == "" {
 		projectPath = a.GetCurrentProjectPath()
 	}
@@ -1094,9 +1103,6 @@ func (a *App) syncToCodeBuddySettings(config AppConfig, projectPath string) erro
 		return nil
 	}
 	cbDir := filepath.Join(projectPath, ".codebuddy")
-	if err := os.MkdirAll(cbDir, 0755); err != nil {
-		return err
-	}
 	cbFilePath := filepath.Join(cbDir, "models.json")
 	var cbModels []CodeBuddyModel
 	var availableModelIds []string
@@ -1162,9 +1168,10 @@ func (a *App) syncToCodeBuddySettings(config AppConfig, projectPath string) erro
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(cbFilePath, data, 0644)
+	files.write(cbFilePath, data)
+	return nil
 }
-func (a *App) syncToQoderSettings(config AppConfig, projectPath string) error {
+func (a *App) syncToQoderSettings(config AppConfig, projectPath string, files *configFileSet) error {
 	if projectPath == "" {
 		projectPath = a.GetCurrentProjectPath()
 	}
@@ -1172,9 +1179,6 @@ func (a *App) syncToQoderSettings(config AppConfig, projectPath string) error {
 		return nil
 	}
 	qDir := filepath.Join(projectPath, ".qoder")
-	if err := os.MkdirAll(qDir, 0755); err != nil {
-		return err
-	}
 	qFilePath := filepath.Join(qDir, "models.json")
 	var qModels []CodeBuddyModel
 	var availableModelIds []string
@@ -1240,7 +1244,8 @@ func (a *App) syncToQoderSettings(config AppConfig, projectPath string) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(qFilePath, data, 0644)
+	files.write(qFilePath, data)
+	return nil
 }
 func getBaseUrl(selectedModel *ModelConfig) string {
 	// If user provided a URL for the selected model, always prefer it.
@@ -1265,9 +1270,41 @@ func getBaseUrl(selectedModel *ModelConfig) string {
 	}
 	return baseUrl
 }
+// findProjectConfig returns the project matching projectDir, falling back to the
+// current project when no project has that path.
+func findProjectConfig(config *AppConfig, projectDir string) *ProjectConfig {
+	for i := range config.Projects {
+		if config.Projects[i].Path == projectDir {
+			return &config.Projects[i]
+		}
+	}
+	for i := range config.Projects {
+		if config.Projects[i].Id == config.CurrentProject {
+			return &config.Projects[i]
+		}
+	}
+	return nil
+}
 func (a *App) LaunchTool(toolName string, yoloMode bool, adminMode bool, pythonProject bool, pythonEnv string, projectDir string, useProxy bool) {
-	a.log(fmt.Sprintf("LaunchTool called: %s, yolo=%v, admin=%v, py=%v, pyenv=%s, dir=%s, proxy=%v",
-		toolName, yoloMode, adminMode, pythonProject, pythonEnv, projectDir, useProxy))
+	a.launchTool(toolName, yoloMode, adminMode, pythonProject, pythonEnv, projectDir, useProxy, "")
+}
+// LaunchToolInSession starts the tool inside a named tmux or zellij session instead
+// of a terminal window, so it can be used over SSH without a display.
+func (a *App) LaunchToolInSession(toolName string, yoloMode bool, pythonProject bool, pythonEnv string, projectDir string, useProxy bool, multiplexer string) (SessionInfo, error) {
+	if multiplexer != LaunchModeTmux && multiplexer != LaunchModeZellij {
+		return SessionInfo{}, fmt.Errorf("unsupported multiplexer: %s", multiplexer)
+	}
+	info, err := a.launchTool(toolName, yoloMode, false, pythonProject, pythonEnv, projectDir, useProxy, multiplexer)
+	if err != nil {
+		return SessionInfo{}, err
+	}
+	return *info, nil
+}
+// launchTool prepares the provider environment and starts the tool. An empty mode
+// uses the project's launch mode; a session launch returns its SessionInfo.
+func (a *App) launchTool(toolName string, yoloMode bool, adminMode bool, pythonProject bool, pythonEnv string, projectDir string, useProxy bool, mode string) (*SessionInfo, error) {
+	a.log(fmt.Sprintf("LaunchTool called: %s, yolo=%v, admin=%v, py=%v, pyenv=%s, dir=%s, proxy=%v, mode=%s",
+		toolName, yoloMode, adminMode, pythonProject, pythonEnv, projectDir, useProxy, mode))
 	a.log(fmt.Sprintf("Launching %s...", toolName))
 	// Only process Python environment if pythonProject is true
 	if pythonProject && pythonEnv != "" && pythonEnv != "None (Default)" {
@@ -1279,10 +1316,220 @@ func (a *App) LaunchTool(toolName string, yoloMode bool, adminMode bool, pythonP
 	if projectDir == "" {
 		projectDir = a.GetCurrentProjectPath()
 	}
+	prep, err := a.buildLaunchPlan(toolName, projectDir, useProxy, "")
+	if err == errNoProvider {
+		title := "提示"
+		message := "请先选择一个服务商。"
+		if a.CurrentLanguage == "en" {
+			title = "Notice"
+			message = "Please select a provider first."
+		}
+		a.ShowMessage(title, message)
+		return nil, fmt.Errorf("no provider selected for %s", toolName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The sandbox hides the home directory, where most Python environments are
+	if prep.sandbox != nil && pythonEnv != "" {
+		if env := a.resolvePythonEnv(pythonEnv, projectDir); env != nil {
+			if prep.sandbox, err = a.sandboxCommand(prep.project.Sandbox, prep.toolName, projectDir, a.pythonEnvSandboxPaths(env)...); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// Remote and container launches get their config files written on the remote
+	// host or in the container
+	remote := prep.project != nil && prep.project.SSHHost != ""
+	contained := useContainer(prep, yoloMode)
+	if mode == "" && prep.project != nil {
+		mode = prep.project.LaunchMode
+	}
+	// A running session keeps the environment and config it was started with, so
+	// nothing is written and no hooks run for it
+	if !remote && !contained && (mode == LaunchModeTmux || mode == LaunchModeZellij) {
+		if info, ok := runningSession(mode, prep.binaryName, projectDir); ok {
+			a.log(fmt.Sprintf("Reusing running %s session %s", mode, info.Name))
+			a.log(fmt.Sprintf("%s is running in %s session %s. Attach with: %s", prep.binaryName, mode, info.Name, info.AttachCommand))
+			a.emitEvent("session-launched", info)
+			return &info, nil
+		}
+	}
+	// Pre-launch hooks run before any config file is written, so a hook that
+	// aborts the launch leaves the tool's configuration alone
+	hc := hookContext{tool: prep.toolName, provider: prep.model.ModelName, modelId: prep.model.ModelId, projectDir: projectDir}
+	if err := a.runPreLaunchHooks(prep.preHooks, hc); err != nil {
+		a.log("Launch aborted: " + err.Error())
+		a.ShowMessage(a.tr("Launch Error"), a.tr("Launch aborted by pre-launch hook: %v", err))
+		return nil, err
+	}
+	if !remote && !contained {
+		if err := a.applyLaunchPlan(prep); err != nil {
+			return nil, err
+		}
+	} else {
+		a.logLaunchPlan(prep)
+	}
+	exitFile := ""
+	if len(prep.postHooks) > 0 {
+		if exitFile, err = a.newExitFile(); err != nil {
+			a.log("Post-exit hooks disabled: " + err.Error())
+			exitFile = ""
+		} else {
+			prep.env[exitFileEnvVar] = exitFile
+		}
+	}
+	if remote {
+		if err := a.launchRemote(prep, yoloMode, exitFile); err != nil {
+			a.log(fmt.Sprintf("Failed to launch %s on %s: %v", prep.binaryName, prep.project.SSHHost, err))
+			a.ShowMessage(a.tr("Launch Error"), a.tr("Failed to launch on remote host: %v", err))
+			return nil, err
+		}
+		if exitFile != "" {
+			a.watchExitFile(exitFile, prep.postHooks, hc)
+		}
+		return nil, nil
+	}
+	if contained {
+		if err := a.launchInContainer(prep, yoloMode, exitFile); err != nil {
+			a.log(fmt.Sprintf("Failed to launch %s in a container: %v", prep.binaryName, err))
+			a.ShowMessage(a.tr("Launch Error"), a.tr("Failed to launch in a container: %v", err))
+			return nil, err
+		}
+		if exitFile != "" {
+			a.watchExitFile(exitFile, prep.postHooks, hc)
+		}
+		return nil, nil
+	}
+	if mode == LaunchModeTmux || mode == LaunchModeZellij {
+		info, err := a.launchInSession(mode, prep.binaryName, yoloMode, pythonEnv, projectDir, prep.env, prep.model.ModelId, prep.extraArgs, prep.sandbox)
+		if err != nil {
+			a.log(fmt.Sprintf("Failed to launch %s in %s session: %v", prep.binaryName, mode, err))
+			return nil, err
+		}
+		a.log(fmt.Sprintf("%s is running in %s session %s. Attach with: %s", prep.binaryName, mode, info.Name, info.AttachCommand))
+		a.emitEvent("session-launched", info)
+		if exitFile != "" && !info.Reused {
+			a.watchExitFile(exitFile, prep.postHooks, hc)
+		}
+		return &info, nil
+	}
+	// Platform specific launch
+	a.platformLaunch(prep.binaryName, yoloMode, adminMode, pythonEnv, projectDir, prep.env, prep.model.ModelId, prep.extraArgs, prep.sandbox)
+	if exitFile != "" {
+		a.watchExitFile(exitFile, prep.postHooks, hc)
+	}
+	return nil, nil
+}
+// launchPlan is everything needed to start a tool in a project: the environment
+// and arguments for the child process and the tool config files to write. It is
+// built without side effects, so several launches can be planned concurrently.
+type launchPlan struct {
+	toolName    string
+	binaryName  string
+	projectDir  string
+	model       *ModelConfig
+	project     *ProjectConfig
+	env         map[string]string
+	proxy       string // "host:port" of the proxy the launch uses, empty for none
+	extraArgs   []string
+	configFiles []ConfigFileChange
+	preHooks    []Hook
+	postHooks   []Hook
+	nodeSpec    string // Requested Node.js version, empty for the default
+	nodeSource  string // Where nodeSpec came from: "project", "tool", ".nvmrc" or ".node-version"
+	node        *NodeVersionInfo // Installed version matching nodeSpec
+	sandbox     []string // Sandbox command the tool command line is appended to
+	persistEnv  bool // Store the provider variables in the user environment (Windows)
+}
+var errNoProvider = errors.New("no provider selected")
+// getToolConfig returns the configuration of a tool by its lower-case name.
+func getToolConfig(config *AppConfig, toolName string) *ToolConfig {
+	switch strings.ToLower(toolName) {
+	case "claude":
+		return &config.Claude
+	case "gemini":
+		return &config.Gemini
+	case "codex":
+		return &config.Codex
+	case "opencode":
+		return &config.Opencode
+	case "codebuddy":
+		return &config.CodeBuddy
+	case "qoder":
+		return &config.Qoder
+	case "iflow":
+		return &config.IFlow
+	case "kilo":
+		return &config.Kilo
+	case "kode":
+		return &config.Kode
+	case "aider":
+		return &config.Aider
+	}
+	return nil
+}
+// prepareLaunch builds the launch plan and writes its tool config files.
+func (a *App) prepareLaunch(toolName string, projectDir string, useProxy bool, provider string) (*launchPlan, error) {
+	plan, err := a.buildLaunchPlan(toolName, projectDir, useProxy, provider)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.applyLaunchPlan(plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+// logLaunchPlan reports the proxy and provider mode of a plan that is about to
+// be launched. Plans that are only previewed are not logged.
+func (a *App) logLaunchPlan(plan *launchPlan) {
+	if plan.proxy != "" {
+		a.log(fmt.Sprintf("Proxy enabled: %s", plan.proxy))
+	}
+	if strings.ToLower(plan.model.ModelName) == "original" {
+		a.log(fmt.Sprintf("Running %s in Original mode: Custom configurations cleared.", plan.toolName))
+	}
+}
+// applyLaunchPlan installs the plan's Node.js version if needed and writes its
+// tool config files on this machine.
+func (a *App) applyLaunchPlan(plan *launchPlan) error {
+	a.logLaunchPlan(plan)
+	if plan.nodeSpec != "" && plan.node == nil {
+		// Install the requested Node.js version on first use; the default Node.js
+		// is used when that fails
+		a.log(a.tr("Node.js %s (from %s) is not installed. Installing...", plan.nodeSpec, plan.nodeSource))
+		if node, err := a.InstallNodeVersion(plan.nodeSpec); err != nil {
+			a.log(a.tr("Failed to install Node.js %s, using the default Node.js: %v", plan.nodeSpec, err))
+		} else {
+			plan.node = &node
+			plan.env[nodeBinEnvVar] = node.Bin
+		}
+	}
+	if plan.node != nil {
+		a.log(a.tr("Using Node.js %s (from %s)", plan.node.Version, plan.nodeSource))
+	}
+	if plan.persistEnv {
+		a.syncToSystemEnv(plan)
+	}
+	if err := a.applyConfigFiles(plan.configFiles); err != nil {
+		a.log(fmt.Sprintf("Failed to write %s configuration: %v", plan.toolName, err))
+		return err
+	}
+	return nil
+}
+// buildLaunchPlan loads the configuration and works out the environment, extra
+// arguments and config files for starting the tool with the selected provider
+// (or the given provider when not empty). Nothing is written.
+func (a *App) buildLaunchPlan(toolName string, projectDir string, useProxy bool, provider string) (*launchPlan, error) {
+	return a.buildLaunchPlanIn(toolName, projectDir, projectDir, useProxy, provider)
+}
+// buildLaunchPlanIn is buildLaunchPlan for a tool working in workDir, e.g. a
+// worktree of the project, with the settings of the project at projectDir.
+func (a *App) buildLaunchPlanIn(toolName string, projectDir string, workDir string, useProxy bool, provider string) (*launchPlan, error) {
 	config, err := a.LoadConfig()
 	if err != nil {
 		a.log("Error loading config: " + err.Error())
-		return
+		return nil, err
 	}
 	var toolCfg ToolConfig
 	var envKey, envBaseUrl string
@@ -1318,6 +1565,11 @@ func (a *App) LaunchTool(toolName string, yoloMode bool, adminMode bool, pythonP
 		envKey = "OPENAI_API_KEY"
 		envBaseUrl = "OPENAI_BASE_URL"
 		binaryName = "kode"
+	case "aider":
+		toolCfg = config.Aider
+		envKey = "OPENAI_API_KEY"
+		envBaseUrl = "OPENAI_API_BASE"
+		binaryName = "aider"
 	case "opencode":
 		toolCfg = config.Opencode
 		envKey = "OPENCODE_API_KEY"
@@ -1334,7 +1586,16 @@ func (a *App) LaunchTool(toolName string, yoloMode bool, adminMode bool, pythonP
 		envBaseUrl = "QODER_BASE_URL"
 		binaryName = "qoder"
 	default:
-		return
+		return nil, fmt.Errorf("unknown tool: %s", toolName)
+	}
+	// Use the requested provider instead of the tool's current one
+	if provider != "" {
+		m := getProviderModel(&toolCfg, provider)
+		if m == nil {
+			return nil, fmt.Errorf("unknown provider %s for %s", provider, toolName)
+		}
+		toolCfg.CurrentModel = m.ModelName
+		getToolConfig(&config, toolName).CurrentModel = m.ModelName
 	}
 	var selectedModel *ModelConfig
 	for _, m := range toolCfg.Models {
@@ -1344,204 +1605,163 @@ func (a *App) LaunchTool(toolName string, yoloMode bool, adminMode bool, pythonP
 		}
 	}
 	if selectedModel == nil || toolCfg.CurrentModel == "" {
-		title := "提示"
-        message := "请先选择一个服务商。"
-		if a.CurrentLanguage == "en" {
-			title = "Notice"
-			message = "Please select a provider first."
-		}
-		a.ShowMessage(title, message)
-		return
+		return nil, errNoProvider
 	}
-	// Ensure ActiveTool is set correctly for syncToSystemEnv
+	// Ensure ActiveTool is set correctly
 	config.ActiveTool = strings.ToLower(toolName)
-	a.syncToSystemEnv(config)
-	// 1. CLEAR PROCESS ENV VARS (Safety First - avoid leaks from current process)
-	a.clearEnvVars()
+	// The environment and config files below belong to this launch only. AICoder's
+	// own process environment is left untouched so concurrent launches cannot leak
+	// into each other.
 	env := make(map[string]string)
-	// Proxy settings
-	if useProxy && goruntime.GOOS != "windows" {
-		var proxyHost, proxyPort, proxyUsername, proxyPassword string
-		// Get proxy configuration (matching project path > global default)
-		var targetProj *ProjectConfig
-		for i := range config.Projects {
-			if config.Projects[i].Path == projectDir {
-				targetProj = &config.Projects[i]
-				break
-			}
-		}
-		// Fallback to CurrentProject if path match not found
-		if targetProj == nil {
-			for i := range config.Projects {
-				if config.Projects[i].Id == config.CurrentProject {
-					targetProj = &config.Projects[i]
-					break
-				}
-			}
-		}
-		if targetProj != nil {
-			proxyHost = targetProj.ProxyHost
-			proxyPort = targetProj.ProxyPort
-			proxyUsername = targetProj.ProxyUsername
-			proxyPassword = targetProj.ProxyPassword
-		}
-		// Use global default if project not configured
-		if proxyHost == "" {
-			proxyHost = config.DefaultProxyHost
-			proxyPort = config.DefaultProxyPort
-			proxyUsername = config.DefaultProxyUsername
-			proxyPassword = config.DefaultProxyPassword
-		}
-		if proxyHost != "" && proxyPort != "" {
-			var proxyURL string
-			if proxyUsername != "" && proxyPassword != "" {
-				proxyURL = fmt.Sprintf("http://%s:%s@%s:%s",
-					proxyUsername, proxyPassword, proxyHost, proxyPort)
+	proxyAddr := ""
+	files := &configFileSet{}
+	// Proxy settings (matching project path > current project > global default)
+	// The provider's proxy policy overrides the project's proxy switch
+	if proxied := providerUsesProxy(selectedModel, useProxy); proxied != useProxy {
+		a.log(fmt.Sprintf("Provider %s proxy policy: %s", selectedModel.ModelName, selectedModel.ProxyPolicy))
+		useProxy = proxied
+	}
+	if useProxy {
+		proxy := resolveProxySettings(&config, findProjectConfig(&config, projectDir))
+		if proxy.Host != "" && proxy.Port != "" {
+			if err := proxy.proxyEnv(env); err != nil {
+				a.log("Proxy not applied: " + err.Error())
 			} else {
-				proxyURL = fmt.Sprintf("http://%s:%s", proxyHost, proxyPort)
+				proxyAddr = proxy.Host + ":" + proxy.Port
 			}
-			// Set proxy environment variables (both cases for compatibility)
-			os.Setenv("HTTP_PROXY", proxyURL)
-			os.Setenv("HTTPS_PROXY", proxyURL)
-			os.Setenv("http_proxy", proxyURL)
-			os.Setenv("https_proxy", proxyURL)
-			env["HTTP_PROXY"] = proxyURL
-			env["HTTPS_PROXY"] = proxyURL
-			env["http_proxy"] = proxyURL
-			env["https_proxy"] = proxyURL
-			a.log(fmt.Sprintf("Proxy enabled: %s:%s", proxyHost, proxyPort))
 		}
 	}
 	if strings.ToLower(selectedModel.ModelName) != "original" {
 		// --- OTHER PROVIDER MODE: WRITE CONFIG & SET ENV ---
-		// Set process environment variables
-		os.Setenv(envKey, selectedModel.ApiKey)
 		env[envKey] = selectedModel.ApiKey
 		if selectedModel.ModelUrl != "" && envBaseUrl != "" {
-			os.Setenv(envBaseUrl, selectedModel.ModelUrl)
 			env[envBaseUrl] = selectedModel.ModelUrl
 		}
 		// Add CODEBUDDY_CODE_MAX_OUTPUT_TOKENS for DeepSeek
 		if strings.ToLower(selectedModel.ModelName) == "deepseek" {
-			os.Setenv("CODEBUDDY_CODE_MAX_OUTPUT_TOKENS", "8192")
 			env["CODEBUDDY_CODE_MAX_OUTPUT_TOKENS"] = "8192"
 		}
 		// Set generic model name env var if applicable
 		if selectedModel.ModelId != "" {
 			switch strings.ToLower(toolName) {
 			case "claude":
-				os.Setenv("ANTHROPIC_MODEL", selectedModel.ModelId)
 				env["ANTHROPIC_MODEL"] = selectedModel.ModelId
 			case "gemini":
-				os.Setenv("GOOGLE_GEMINI_MODEL", selectedModel.ModelId)
 				env["GOOGLE_GEMINI_MODEL"] = selectedModel.ModelId
 			case "codex":
-				os.Setenv("OPENAI_MODEL", selectedModel.ModelId)
 				env["OPENAI_MODEL"] = selectedModel.ModelId
 			case "opencode":
-				os.Setenv("OPENCODE_MODEL", selectedModel.ModelId)
 				env["OPENCODE_MODEL"] = selectedModel.ModelId
 			case "codebuddy":
-				// os.Setenv("CODEBUDDY_MODEL", selectedModel.ModelId)
 				// env["CODEBUDDY_MODEL"] = selectedModel.ModelId
 			case "qoder":
 				// Qoder doesn't use model env var
 			case "iflow":
 				// iFlow uses settings.json, but maybe env var too?
-				os.Setenv("IFLOW_MODEL", selectedModel.ModelId)
 				env["IFLOW_MODEL"] = selectedModel.ModelId
 			case "kilo":
-				os.Setenv("KILO_MODEL", selectedModel.ModelId)
 				env["KILO_MODEL"] = selectedModel.ModelId
 			}
 		}
 		// Tool-specific configurations
 		switch strings.ToLower(toolName) {
 		case "claude":
-			// Ensure AUTH_TOKEN is unset when using API_KEY to avoid conflict
-			a.syncToClaudeSettings(config)
+			a.syncToClaudeSettings(config, files)
 		case "gemini":
-			a.syncToGeminiSettings(config)
+			a.syncToGeminiSettings(config, files)
 		case "codex":
-			os.Setenv("WIRE_API", "responses")
 			env["WIRE_API"] = "responses"
 			// Ensure OpenAI standard vars for Codex
-			os.Setenv("OPENAI_API_KEY", selectedModel.ApiKey)
 			env["OPENAI_API_KEY"] = selectedModel.ApiKey
 			if selectedModel.ModelUrl != "" {
-				os.Setenv("OPENAI_BASE_URL", selectedModel.ModelUrl)
 				env["OPENAI_BASE_URL"] = selectedModel.ModelUrl
 			}
-			a.syncToCodexSettings(config)
+			a.syncToCodexSettings(config, files)
 		case "opencode":
 			// Opencode might use similar settings to Codex or its own
-			a.syncToOpencodeSettings(config)
+			a.syncToOpencodeSettings(config, files)
 		case "codebuddy":
-			// a.syncToCodeBuddySettings(config, projectDir)
+			// a.syncToCodeBuddySettings(config, projectDir, files)
 		case "qoder":
-			a.syncToQoderSettings(config, projectDir)
+			a.syncToQoderSettings(config, workDir, files)
 		case "iflow":
 			// Ensure OpenAI standard vars for iFlow (compatibility)
-			os.Setenv("OPENAI_API_KEY", selectedModel.ApiKey)
 			env["OPENAI_API_KEY"] = selectedModel.ApiKey
 			if selectedModel.ModelUrl != "" {
-				os.Setenv("OPENAI_BASE_URL", selectedModel.ModelUrl)
 				env["OPENAI_BASE_URL"] = selectedModel.ModelUrl
 			}
-			a.syncToIFlowSettings(config)
+			a.syncToIFlowSettings(config, files)
 		case "kilo":
 			// Configure Kilo Code settings
-			a.syncToKiloSettings(config)
+			a.syncToKiloSettings(config, files)
 		case "kode":
 			// Configure Kode CLI settings - uses .kode.json configuration file
-			a.syncToKodeSettings(config)
+			a.syncToKodeSettings(config, files)
+		case "aider":
+			// Aider is configured through the environment only
+			aiderProviderEnv(selectedModel, env)
 		}
 	} else {
 		// --- ORIGINAL MODE: CLEANUP SPECIFIC TOOL ONLY ---
-		// Clear process environment variables for this tool
-		os.Unsetenv(envKey)
-		os.Unsetenv(envBaseUrl)
-		if strings.ToLower(toolName) == "claude" {
-			os.Unsetenv("ANTHROPIC_AUTH_TOKEN")
-			os.Unsetenv("ANTHROPIC_MODEL")
-			a.clearClaudeConfig()
-		} else if strings.ToLower(toolName) == "gemini" {
-			os.Unsetenv("GOOGLE_GEMINI_MODEL")
-			a.syncToGeminiSettings(config)
-		} else if strings.ToLower(toolName) == "codex" {
-			os.Unsetenv("WIRE_API")
-			os.Unsetenv("OPENAI_API_KEY")
-			os.Unsetenv("OPENAI_BASE_URL")
-			os.Unsetenv("OPENAI_MODEL")
-			a.clearCodexConfig()
-		} else if strings.ToLower(toolName) == "opencode" {
-			os.Unsetenv("OPENCODE_API_KEY")
-			os.Unsetenv("OPENCODE_BASE_URL")
-			os.Unsetenv("OPENCODE_MODEL")
-			a.clearOpencodeConfig()
-		} else if strings.ToLower(toolName) == "codebuddy" {
-			os.Unsetenv("CODEBUDDY_API_KEY")
-			os.Unsetenv("CODEBUDDY_BASE_URL")
-			os.Unsetenv("CODEBUDDY_CODE_MAX_OUTPUT_TOKENS")
-			// Codebuddy might need cleanup too if we added a clear function
-		} else if strings.ToLower(toolName) == "qoder" {
-			os.Unsetenv("QODER_PERSONAL_ACCESS_TOKEN")
-			os.Unsetenv("QODER_BASE_URL")
-			// Qoder cleanup if needed
-		} else if strings.ToLower(toolName) == "iflow" {
-			os.Unsetenv("IFLOW_MODEL")
-			a.clearIFlowConfig()
-		} else if strings.ToLower(toolName) == "kilo" {
-			os.Unsetenv("KILO_MODEL")
-			a.clearKiloConfig()
-		} else if strings.ToLower(toolName) == "kode" {
-			os.Unsetenv("KODE_MODEL")
-			a.clearKodeConfig()
-		}
-		a.log(fmt.Sprintf("Running %s in Original mode: Custom configurations cleared.", toolName))
-	}
-	// Platform specific launch
-	a.platformLaunch(binaryName, yoloMode, adminMode, pythonEnv, projectDir, env, selectedModel.ModelId)
+		// Provider variables are not set, and the launch script unsets any
+		// inherited ones (see providerEnvVars)
+		switch strings.ToLower(toolName) {
+		case "claude":
+			a.clearClaudeConfig(files)
+		case "gemini":
+			a.syncToGeminiSettings(config, files)
+		case "codex":
+			a.clearCodexConfig(files)
+		case "opencode":
+			a.clearOpencodeConfig(files)
+		case "iflow":
+			a.clearIFlowConfig(files)
+		case "kilo":
+			a.clearKiloConfig(files)
+		case "kode":
+			a.clearKodeConfig(files)
+		}
+	}
+	// Extra arguments and environment (tool < provider < project)
+	proj := findProjectConfig(&config, projectDir)
+	extraArgs, extraEnv := collectLaunchExtras(&toolCfg, selectedModel, proj)
+	extraArgs = a.applyLaunchExtras(extraArgs, extraEnv, a.launchVariables(toolName, selectedModel, workDir), env)
+	if len(extraArgs) > 0 || len(extraEnv) > 0 {
+		a.log(fmt.Sprintf("Extra launch options: %d argument(s), %d environment variable(s)", len(extraArgs), len(extraEnv)))
+	}
+	// Sandbox (Linux)
+	var sandbox []string
+	if proj != nil && proj.SSHHost == "" {
+		if sandbox, err = a.sandboxCommand(proj.Sandbox, strings.ToLower(toolName), workDir); err != nil {
+			return nil, err
+		}
+	}
+	// Node.js version (project > .nvmrc/.node-version > tool)
+	nodeSpec, nodeSource := launchNodeVersion(&toolCfg, proj, workDir)
+	var node *NodeVersionInfo
+	if nodeSpec != "" {
+		if node = a.findNodeVersion(nodeSpec); node != nil {
+			env[nodeBinEnvVar] = node.Bin
+		}
+	}
+	return &launchPlan{
+		toolName:    strings.ToLower(toolName),
+		binaryName:  binaryName,
+		projectDir:  workDir,
+		model:       selectedModel,
+		project:     proj,
+		env:         env,
+		proxy:       proxyAddr,
+		extraArgs:   extraArgs,
+		configFiles: files.changes,
+		preHooks:    collectHooks(&config, proj, HookStagePreLaunch),
+		postHooks:   collectHooks(&config, proj, HookStagePostExit),
+		nodeSpec:    nodeSpec,
+		nodeSource:  nodeSource,
+		node:        node,
+		sandbox:     sandbox,
+		persistEnv:  config.PersistProviderEnv,
+	}, nil
 }
 func (a *App) log(message string) {
 	if a.IsInitMode {
@@ -1654,6 +1874,16 @@ func (a *App) LoadConfig() (AppConfig, error) {
 		{ModelName: "Custom", ModelId: "", ModelUrl: "", ApiKey: "", IsCustom: true},
 		{ModelName: "Custom1", ModelId: "", ModelUrl: "", ApiKey: "", IsCustom: true},
 	}
+	defaultAiderModels := []ModelConfig{
+		{ModelName: "Original", ModelId: "", ModelUrl: "", ApiKey: ""},
+		{ModelName: "DeepSeek", ModelId: "deepseek-chat", ModelUrl: "https://api.deepseek.com/v1", ApiKey: ""},
+		{ModelName: "GLM", ModelId: "glm-4.7", ModelUrl: "https://open.bigmodel.cn/api/paas/v4", ApiKey: ""},
+		{ModelName: "Kimi", ModelId: "kimi-for-coding", ModelUrl: "https://api.kimi.com/coding/v1", ApiKey: ""},
+		{ModelName: "MiniMax", ModelId: "MiniMax-M2.1", ModelUrl: "https://api.minimaxi.com/anthropic", ApiKey: ""},
+		{ModelName: "ChatFire", ModelId: "gpt-4o", ModelUrl: "https://api.chatfire.cn/v1", ApiKey: ""},
+		{ModelName: "Custom", ModelId: "", ModelUrl: "", ApiKey: "", IsCustom: true},
+		{ModelName: "Custom1", ModelId: "", ModelUrl: "", ApiKey: "", IsCustom: true},
+	}
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		// Check for old config file for migration
 		home, _ := os.UserHomeDir()
@@ -1706,6 +1936,10 @@ func (a *App) LoadConfig() (AppConfig, error) {
 						CurrentModel: "ChatFire",
 						Models:       defaultKodeModels,
 					},
+						Aider: ToolConfig{
+							CurrentModel: "Original",
+							Models:       defaultAiderModels,
+						},
 						Projects:       oldConfig.Projects,
 						CurrentProject: oldConfig.CurrentProj,
 						ActiveTool:     "claude",
@@ -1762,6 +1996,10 @@ func (a *App) LoadConfig() (AppConfig, error) {
 			CurrentModel: "ChatFire",
 			Models:       defaultKodeModels,
 		},
+			Aider: ToolConfig{
+				CurrentModel: "Original",
+				Models:       defaultAiderModels,
+			},
 			Projects: []ProjectConfig{
 				{
 					Id:       "default",
@@ -1878,6 +2116,10 @@ func (a *App) LoadConfig() (AppConfig, error) {
 		config.Kode.Models = defaultKodeModels
 		config.Kode.CurrentModel = "ChatFire"
 	}
+	if config.Aider.Models == nil || len(config.Aider.Models) == 0 {
+		config.Aider.Models = defaultAiderModels
+		config.Aider.CurrentModel = "Original"
+	}
 	ensureModel(&config.Claude.Models, "AiCodeMirror", "https://api.aicodemirror.com/api/claudecode", "sonnet", "")
 	ensureModel(&config.Claude.Models, "Noin.AI", "https://ai.ourines.com/api", "sonnet", "")
 	ensureModel(&config.Claude.Models, "AIgoCode", "https://api.aigocode.com/api", "sonnet", "")
@@ -2022,22 +2264,30 @@ func (a *App) LoadConfig() (AppConfig, error) {
 	ensureCustom(&config.IFlow.Models)
 	ensureCustom(&config.Kilo.Models)
 	ensureCustom(&config.Kode.Models)
+	ensureCustom(&config.Aider.Models)
 	// Qoder only has Original and Qoder
 	// Preserve existing Qoder key if present
 	var existingQoderKey string
-	for _, m := range config.Qoder.Models {
+	var existingQoderExtras *ModelConfig
+	for i, m := range config.Qoder.Models {
 		if m.ModelName == "Qoder" {
 			existingQoderKey = m.ApiKey
+			existingQoderExtras = &config.Qoder.Models[i]
 			break
 		}
 	}
 	config.Qoder.Models = defaultQoderModels
-	if existingQoderKey != "" {
-		for i := range config.Qoder.Models {
-			if config.Qoder.Models[i].ModelName == "Qoder" {
+	for i := range config.Qoder.Models {
+		if config.Qoder.Models[i].ModelName == "Qoder" {
+			if existingQoderKey != "" {
 				config.Qoder.Models[i].ApiKey = existingQoderKey
-				break
 			}
+			if existingQoderExtras != nil {
+				config.Qoder.Models[i].ExtraArgs = existingQoderExtras.ExtraArgs
+				config.Qoder.Models[i].ExtraEnv = existingQoderExtras.ExtraEnv
+				config.Qoder.Models[i].ProxyPolicy = existingQoderExtras.ProxyPolicy
+			}
+			break
 		}
 	}
 	// Ensure 'Custom' and 'Custom1' are always last for all tools
@@ -2094,6 +2344,7 @@ func (a *App) LoadConfig() (AppConfig, error) {
 	moveCustomToLast(&config.IFlow.Models)
 	moveCustomToLast(&config.Kilo.Models)
 	moveCustomToLast(&config.Kode.Models)
+	moveCustomToLast(&config.Aider.Models)
 	ensureOriginalFirst(&config.Claude.Models)
 	ensureOriginalFirst(&config.Gemini.Models)
 	ensureOriginalFirst(&config.Codex.Models)
@@ -2102,6 +2353,7 @@ func (a *App) LoadConfig() (AppConfig, error) {
 	ensureOriginalFirst(&config.Qoder.Models)
 	ensureOriginalFirst(&config.IFlow.Models)
 	ensureOriginalFirst(&config.Kilo.Models)
+	ensureOriginalFirst(&config.Aider.Models)
 	// Ensure CurrentModel is valid
 	if config.Gemini.CurrentModel == "" {
 		config.Gemini.CurrentModel = "Original"
@@ -2135,6 +2387,9 @@ func (a *App) LoadConfig() (AppConfig, error) {
 	if config.Kode.CurrentModel == "" {
 		config.Kode.CurrentModel = "ChatFire"
 	}
+	if config.Aider.CurrentModel == "" {
+		config.Aider.CurrentModel = "Original"
+	}
 	if config.ActiveTool == "" {
 		config.ActiveTool = "message"
 	}
@@ -2156,6 +2411,7 @@ func (a *App) LoadConfig() (AppConfig, error) {
 	normalizeCurrentModel(&config.IFlow)
 	normalizeCurrentModel(&config.Kilo)
 	normalizeCurrentModel(&config.Kode)
+	normalizeCurrentModel(&config.Aider)
 	return config, nil
 }
 // getProviderModel gets the model for a specific provider name from a tool config
@@ -2180,6 +2436,7 @@ func syncAllProviderApiKeys(a *App, oldConfig, newConfig *AppConfig) {
 		"iflow":     &newConfig.IFlow,
 		"kilo":      &newConfig.Kilo,
 		"kode":      &newConfig.Kode,
+		"aider":     &newConfig.Aider,
 	}
 	oldTools := map[string]*ToolConfig{
 		"claude":    &oldConfig.Claude,
@@ -2191,6 +2448,7 @@ func syncAllProviderApiKeys(a *App, oldConfig, newConfig *AppConfig) {
 		"iflow":     &oldConfig.IFlow,
 		"kilo":      &oldConfig.Kilo,
 		"kode":      &oldConfig.Kode,
+		"aider":     &oldConfig.Aider,
 	}
 	// providerName (lower) -> intended API key
 	intentions := make(map[string]string)
@@ -2267,6 +2525,7 @@ func (a *App) SaveConfig(config AppConfig) error {
 	sanitizeCustomNames(config.IFlow.Models)
 	sanitizeCustomNames(config.Kilo.Models)
 	sanitizeCustomNames(config.Kode.Models)
+	sanitizeCustomNames(config.Aider.Models)
 	// Load old config to compare for sync logic
 	var oldConfig AppConfig
 	path, _ := a.getConfigPath()
@@ -2275,6 +2534,8 @@ func (a *App) SaveConfig(config AppConfig) error {
 	}
 	// Sync all apikeys across all tools before saving
 	syncAllProviderApiKeys(a, &oldConfig, &config)
+	// Pre-select project-local Python environments for newly opened projects
+	a.preselectProjectPythonEnvs(&oldConfig, &config)
 	if err := a.saveToPath(path, config); err != nil {
 		return err
 	}
@@ -2822,32 +3083,36 @@ func (a *App) getLatestNpmVersion(npmPath string, packageName string) (string, e
 	if err := os.MkdirAll(localCacheDir, 0755); err != nil {
 		a.log(fmt.Sprintf("Warning: Failed to create local npm cache dir: %v", err))
 	}
-	args := []string{"view", packageName, "version", "--cache", localCacheDir}
-	if strings.HasPrefix(strings.ToLower(a.CurrentLanguage), "zh") {
-		args = append(args, "--registry=https://registry.npmmirror.com")
-	}
+	args := []string{"view", packageName, "version", "--json", "--cache", localCacheDir}
+	args = append(args, a.npmRegistryArgs(true)...)
 	cmd = createNpmInstallCmd(npmPath, args) // Using createNpmInstallCmd as it's a general npm command runner
-	out, err := cmd.Output()
+	out, err := outputWithTimeout(cmd, npmViewTimeout)
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(string(out)), nil
+	return parseNpmViewVersion(out)
 }
-// ListPythonEnvironments returns a list of all available Python environments
-func (a *App) ListPythonEnvironments() []PythonEnvironment {
-	envs := []PythonEnvironment{}
-	// Add default "None" option
-	envs = append(envs, PythonEnvironment{
-		Name: "None (Default)",
-		Path: "",
-		Type: "system",
-	})
-	// Detect Conda environments
-	condaEnvs := a.detectCondaEnvironments()
-	envs = append(envs, condaEnvs...)
-	// Could add detection for virtualenv, venv, etc. here
-	return envs
+// parseNpmViewVersion returns the highest version in the output of
+// `npm view <pkg>@<range> version --json`: a single version string, or an
+// array of them when several versions match the range.
+func parseNpmViewVersion(out []byte) (string, error) {
+	var version string
+	if err := json.Unmarshal(out, &version); err == nil && version != "" {
+		return version, nil
+	}
+	var versions []string
+	if err := json.Unmarshal(out, &versions); err != nil || len(versions) == 0 {
+		return "", fmt.Errorf("no matching version found: %s", strings.TrimSpace(string(out)))
+	}
+	latest := versions[0]
+	for _, v := range versions[1:] {
+		if compareVersions(v, latest) > 0 {
+			latest = v
+		}
+	}
+	return latest, nil
 }
+// ListPythonEnvironments returns a list of all available Python environments
 // detectCondaEnvironments finds all Anaconda/Miniconda environments
 func (a *App) detectCondaEnvironments() []PythonEnvironment {
 	envs := []PythonEnvironment{}
@@ -4119,6 +4384,98 @@ var translations = map[string]map[string]string{
 		"zh-Hans": "发现 conda 位于: ",
 		"zh-Hant": "發現 conda 位於: ",
 	},
+	"Launch Error": {
+		"zh-Hans": "启动错误",
+		"zh-Hant": "啟動錯誤",
+	},
+	"Failed to open a terminal: %v": {
+		"zh-Hans": "无法打开终端: %v",
+		"zh-Hant": "無法開啟終端: %v",
+	},
+	"Launch aborted by pre-launch hook: %v": {
+		"zh-Hans": "启动前钩子失败，已取消启动: %v",
+		"zh-Hant": "啟動前鉤子失敗，已取消啟動: %v",
+	},
+	"Node.js %s installed to %s": {
+		"zh-Hans": "Node.js %s 已安装到 %s",
+		"zh-Hant": "Node.js %s 已安裝到 %s",
+	},
+	"Node.js %s (from %s) is not installed. Installing...": {
+		"zh-Hans": "Node.js %s（来自 %s）尚未安装，正在安装...",
+		"zh-Hant": "Node.js %s（來自 %s）尚未安裝，正在安裝...",
+	},
+	"Failed to install Node.js %s, using the default Node.js: %v": {
+		"zh-Hans": "安装 Node.js %s 失败，将使用默认 Node.js: %v",
+		"zh-Hant": "安裝 Node.js %s 失敗，將使用預設 Node.js: %v",
+	},
+	"Failed to launch on remote host: %v": {
+		"zh-Hans": "无法在远程主机上启动: %v",
+		"zh-Hant": "無法在遠端主機上啟動: %v",
+	},
+	"Failed to launch in a container: %v": {
+		"zh-Hans": "无法在容器中启动: %v",
+		"zh-Hant": "無法在容器中啟動: %v",
+	},
+	"Using Node.js %s (from %s)": {
+		"zh-Hans": "使用 Node.js %s（来自 %s）",
+		"zh-Hant": "使用 Node.js %s（來自 %s）",
+	},
+	"Rolled back %s to %s": {
+		"zh-Hans": "已将 %s 回滚到 %s",
+		"zh-Hant": "已將 %s 回滾到 %s",
+	},
+	"%s is pinned to %s, skipping update": {
+		"zh-Hans": "%s 已固定为 %s 版本，跳过更新",
+		"zh-Hant": "%s 已固定為 %s 版本，略過更新",
+	},
+	"Failed to update %s: %v": {
+		"zh-Hans": "更新 %s 失败: %v",
+		"zh-Hant": "更新 %s 失敗: %v",
+	},
+	"Tool updates finished: %d succeeded, %d failed": {
+		"zh-Hans": "工具更新完成：%d 个成功，%d 个失败",
+		"zh-Hant": "工具更新完成：%d 個成功，%d 個失敗",
+	},
+	"Using npm registry %s (%d ms)": {
+		"zh-Hans": "使用 npm 源 %s（%d 毫秒）",
+		"zh-Hant": "使用 npm 源 %s（%d 毫秒）",
+	},
+	"Warning: no local Node.js runtime in %s, the bundle needs Node.js on the target machine": {
+		"zh-Hans": "警告: %s 中没有本地 Node.js 运行时，目标机器上需要已安装 Node.js",
+		"zh-Hant": "警告: %s 中沒有本機 Node.js 執行環境，目標機器上需要已安裝 Node.js",
+	},
+	"Tools bundle written to %s (%d files)": {
+		"zh-Hans": "工具离线包已写入 %s（%d 个文件）",
+		"zh-Hant": "工具離線包已寫入 %s（%d 個檔案）",
+	},
+	"Tools bundle imported from %s": {
+		"zh-Hans": "已从 %s 导入工具离线包",
+		"zh-Hant": "已從 %s 匯入工具離線包",
+	},
+	"Downloading %s %s from %s...": {
+		"zh-Hans": "正在从 %[3]s 下载 %[1]s %[2]s...",
+		"zh-Hant": "正在從 %[3]s 下載 %[1]s %[2]s...",
+	},
+	"Warning: no checksum published for %s, the download of %s is not verified": {
+		"zh-Hans": "警告：%s 没有发布校验和，%s 的下载未经校验",
+		"zh-Hant": "警告：%s 沒有發佈校驗和，%s 的下載未經校驗",
+	},
+	"Uninstalling %s...": {
+		"zh-Hans": "正在卸载 %s...",
+		"zh-Hant": "正在解除安裝 %s...",
+	},
+	"Removed %s": {
+		"zh-Hans": "已删除 %s",
+		"zh-Hant": "已刪除 %s",
+	},
+	"%s uninstalled": {
+		"zh-Hans": "%s 已卸载",
+		"zh-Hant": "%s 已解除安裝",
+	},
+	"Freed %.1f MB": {
+		"zh-Hans": "已释放 %.1f MB",
+		"zh-Hant": "已釋放 %.1f MB",
+	},
 }
 func (a *App) tr(key string, args ...interface{}) string {
 	lang := strings.ToLower(a.CurrentLanguage)
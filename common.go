@@ -9,6 +9,7 @@ var trayTranslations = map[string]map[string]string{
 		"quit":    "Quit AICoder",
 		"models":  "Providers",
 		"actions": "Actions",
+		"proxied": "%s (proxy)",
 	},
 	"zh-Hans": {
 		"title":   "AICoder 控制台",
@@ -18,6 +19,7 @@ var trayTranslations = map[string]map[string]string{
 		"quit":    "退出程序",
 		"models":  "服务商选择",
 		"actions": "操作",
+		"proxied": "%s（代理）",
 	},
 	"zh-Hant": {
 		"title":   "AICoder 控制台",
@@ -27,6 +29,7 @@ var trayTranslations = map[string]map[string]string{
 		"quit":    "退出程式",
 		"models":  "服務商選擇",
 		"actions": "操作",
+		"proxied": "%s（代理）",
 	},
 }
 
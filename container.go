@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultContainerImage has bash, git and npm, which is all the tools need.
+	defaultContainerImage = "docker.io/library/node:22-bookworm"
+	// Paths inside the container
+	containerHome      = "/home/aicoder"
+	containerWorkspace = "/workspace"
+	containerScript    = "/aicoder/launch.sh"
+)
+
+// useContainer reports whether a launch of the plan runs in a container: only
+// yolo launches of projects with container mode on, and never remote ones.
+func useContainer(plan *launchPlan, yoloMode bool) bool {
+	proj := plan.project
+	return yoloMode && proj != nil && proj.ContainerMode && proj.SSHHost == ""
+}
+
+// findContainerRuntime returns the docker or podman binary to use. An empty
+// preference picks docker first.
+func findContainerRuntime(preference string) (string, string, error) {
+	candidates := []string{"docker", "podman"}
+	if preference != "" {
+		candidates = []string{preference}
+	}
+	for _, name := range candidates {
+		if path, err := exec.LookPath(name); err == nil {
+			return name, path, nil
+		}
+	}
+	return "", "", fmt.Errorf("%s not found in PATH", strings.Join(candidates, " or "))
+}
+
+// containerRunArgs returns the "run" arguments that start the tool in a
+// throwaway container. Only the project directory and AICoder's container
+// tools cache are mounted; the home directory inside is an empty tmpfs that
+// only gets the keys AICoder writes, not the host's own tool state.
+func (a *App) containerRunArgs(runtimeName string, plan *launchPlan, scriptPath string) []string {
+	proj := plan.project
+	dataDir := filepath.Join(a.GetUserHomeDir(), ".cceasy", "container")
+	image := proj.ContainerImage
+	if image == "" {
+		image = defaultContainerImage
+	}
+
+	args := []string{"run", "--rm", "-it",
+		"--name", fmt.Sprintf("aicoder-%s-%d", plan.binaryName, time.Now().Unix()),
+		"--tmpfs", containerHome + ":exec,mode=1777",
+		"-e", "HOME=" + containerHome,
+		"-v", plan.projectDir + ":" + containerWorkspace,
+		"-v", filepath.Join(dataDir, "tools") + ":" + containerHome + "/.cceasy/tools",
+		"-v", filepath.Join(dataDir, "cache") + ":" + containerHome + "/.cc/cache",
+		"-v", scriptPath + ":" + containerScript + ":ro",
+		"-w", containerWorkspace,
+	}
+	// Files created in the project must belong to the user, not to root
+	if runtimeName == "podman" {
+		args = append(args, "--userns=keep-id")
+	} else if runtime.GOOS == "linux" {
+		args = append(args, "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()))
+	}
+	if proj.ContainerNetwork != "" {
+		args = append(args, "--network", proj.ContainerNetwork)
+	}
+	// Docker Desktop and podman resolve the host's name themselves
+	if runtimeName == "docker" && runtime.GOOS == "linux" && proj.ContainerNetwork != "host" {
+		args = append(args, "--add-host", "host.docker.internal:host-gateway")
+	}
+	return append(args, image, "bash", containerScript)
+}
+
+// containerProxyEnv returns env with proxies on the loopback interface pointed
+// at the host, since inside the container the loopback interface is the
+// container's own. It reports whether any proxy was changed.
+func containerProxyEnv(env map[string]string, runtimeName, network string) (map[string]string, bool) {
+	host := "host.docker.internal"
+	if runtimeName == "podman" {
+		host = "host.containers.internal"
+	}
+	result := make(map[string]string, len(env))
+	changed := false
+	for k, v := range env {
+		result[k] = v
+		if network == "host" || !strings.HasSuffix(strings.ToUpper(k), "_PROXY") || strings.EqualFold(k, "NO_PROXY") {
+			continue
+		}
+		u, err := url.Parse(v)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		switch u.Hostname() {
+		case "127.0.0.1", "localhost", "::1":
+			if u.Port() != "" {
+				u.Host = net.JoinHostPort(host, u.Port())
+			} else {
+				u.Host = host
+			}
+			result[k] = u.String()
+			changed = true
+		}
+	}
+	return result, changed
+}
+
+// launchInContainer runs the tool of a plan in a Docker or Podman container in
+// a local terminal. The tool is installed into a cache shared by all container
+// launches on first use. exitFile, when set, receives the exit code.
+func (a *App) launchInContainer(plan *launchPlan, yoloMode bool, exitFile string) error {
+	runtimeName, runtimePath, err := findContainerRuntime(plan.project.ContainerRuntime)
+	if err != nil {
+		return err
+	}
+
+	dataDir := filepath.Join(a.GetUserHomeDir(), ".cceasy", "container")
+	for _, dir := range []string{filepath.Join(dataDir, "tools"), filepath.Join(dataDir, "cache")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	if env, changed := containerProxyEnv(plan.env, runtimeName, plan.project.ContainerNetwork); changed {
+		a.log("Proxy on localhost: the container connects to it through the host, so it must accept connections from the container network")
+		contained := *plan
+		contained.env = env
+		plan = &contained
+	}
+
+	args := append(toolLaunchArgs(plan.binaryName, yoloMode, plan.model.ModelId), plan.extraArgs...)
+	script := "#!/bin/bash\n" + a.buildPortableLaunchCommands(plan, args, containerWorkspace, true, true)
+	scriptPath := filepath.Join(dataDir, fmt.Sprintf("launch_%d.sh", time.Now().UnixNano()))
+	if err := os.WriteFile(scriptPath, []byte(script), 0600); err != nil {
+		return fmt.Errorf("failed to write container launch script: %v", err)
+	}
+
+	run := terminalCommand{Args: append([]string{runtimePath}, a.containerRunArgs(runtimeName, plan, scriptPath)...)}
+	a.log(fmt.Sprintf("Launching %s in a %s container", plan.binaryName, runtimeName))
+	if err := a.runInTerminal([]terminalCommand{run}, []string{scriptPath}, exitFile, "AICoder - "+plan.binaryName+" (container)"); err != nil {
+		os.Remove(scriptPath)
+		return err
+	}
+	return nil
+}
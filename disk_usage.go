@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Disk usage categories. Only the cleanable ones can be passed to
+// CleanDiskUsage.
+const (
+	DiskUsageTools         = "tools"          // Active tool installations and the Node.js runtime in ~/.cceasy/tools
+	DiskUsageStaleVersions = "stale_versions" // Kept versions other than the active one, and leftovers of failed installs
+	DiskUsageNode          = "node"           // Node.js versions for launches
+	DiskUsageNpmCache      = "npm_cache"      // npm's cache in GetLocalCacheDir
+	DiskUsageSkills        = "skills"
+	DiskUsageDownloads     = "downloads" // Downloaded installers and archives left in the temp directory
+	DiskUsageBundles       = "bundles"   // Exported tools bundles
+	DiskUsageRuns          = "runs"      // Output of prompt runs
+	DiskUsageOther         = "other"     // Everything else in ~/.cceasy
+)
+
+// staleAge is how old the directory of an install or import, or a download,
+// must be before it counts as left over rather than still in use.
+const staleAge = time.Hour
+
+// DiskUsageCategory is the space used by one category.
+type DiskUsageCategory struct {
+	Name      string   `json:"name"`
+	Paths     []string `json:"paths"`
+	Bytes     int64    `json:"bytes"`
+	Cleanable bool     `json:"cleanable"`
+}
+
+// ToolDiskUsage is the space used by the active installation of a tool.
+type ToolDiskUsage struct {
+	Name  string `json:"name"`
+	Bytes int64  `json:"bytes"`
+}
+
+// DiskUsage is the space AICoder uses, by category and by tool.
+type DiskUsage struct {
+	Categories []DiskUsageCategory `json:"categories"`
+	Tools      []ToolDiskUsage     `json:"tools"`
+	Total      int64               `json:"total"`
+}
+
+// pathSize returns the size of the files under path. Links are not followed.
+func pathSize(path string) int64 {
+	var size int64
+	filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+func isStale(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && time.Since(info.ModTime()) > staleAge
+}
+
+// staleToolPaths returns the kept versions that are not active, and the
+// staging directories of installs that did not finish.
+func (tm *ToolManager) staleToolPaths() []string {
+	var paths []string
+	versionsRoot := filepath.Join(tm.toolsDir(), "versions")
+	tools, _ := os.ReadDir(versionsRoot)
+	for _, t := range tools {
+		if !t.IsDir() {
+			continue
+		}
+		active := tm.loadVersionState(t.Name()).Active
+		entries, _ := os.ReadDir(filepath.Join(versionsRoot, t.Name()))
+		for _, e := range entries {
+			path := filepath.Join(versionsRoot, t.Name(), e.Name())
+			if !e.IsDir() || e.Name() == active {
+				continue
+			}
+			if strings.HasPrefix(e.Name(), ".install-") && !isStale(path) {
+				continue
+			}
+			paths = append(paths, path)
+		}
+	}
+	// Environments set aside by a Python tool install that was interrupted
+	envs, _ := filepath.Glob(filepath.Join(tm.toolsDir(), "py", "*.old-*"))
+	for _, env := range envs {
+		if isStale(env) {
+			paths = append(paths, env)
+		}
+	}
+	return paths
+}
+
+// downloadLeftovers returns downloaded installers and archives that were not
+// removed, and the staging directories of bundle imports that did not finish.
+// Recent ones are left alone, as they may still be in use.
+func (a *App) downloadLeftovers() []string {
+	var paths []string
+	for _, pattern := range []string{"node-v*", "Git-*.exe", "aicoder_log_*.zip"} {
+		matches, _ := filepath.Glob(filepath.Join(os.TempDir(), pattern))
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && !info.IsDir() && isStale(m) {
+				paths = append(paths, m)
+			}
+		}
+	}
+	imports, _ := filepath.Glob(filepath.Join(a.GetUserHomeDir(), ".cceasy", ".import-*"))
+	for _, dir := range imports {
+		if isStale(dir) {
+			paths = append(paths, dir)
+		}
+	}
+	return paths
+}
+
+// DiskUsage reports the space used by ~/.cceasy, the npm cache and leftover
+// downloads, by category and by tool.
+func (a *App) DiskUsage() DiskUsage {
+	tm := NewToolManager(a)
+	ccDir := filepath.Join(a.GetUserHomeDir(), ".cceasy")
+	usage := DiskUsage{Tools: []ToolDiskUsage{}}
+	add := func(name string, cleanable bool, paths ...string) int64 {
+		c := DiskUsageCategory{Name: name, Paths: paths, Cleanable: cleanable}
+		if c.Paths == nil {
+			c.Paths = []string{}
+		}
+		for _, p := range paths {
+			c.Bytes += pathSize(p)
+		}
+		usage.Categories = append(usage.Categories, c)
+		usage.Total += c.Bytes
+		return c.Bytes
+	}
+
+	stale := tm.staleToolPaths()
+	var staleBytes int64
+	for _, p := range stale {
+		staleBytes += pathSize(p)
+	}
+	toolsBytes := pathSize(tm.toolsDir()) - staleBytes
+	usage.Categories = append(usage.Categories, DiskUsageCategory{Name: DiskUsageTools, Paths: []string{tm.toolsDir()}, Bytes: toolsBytes})
+	usage.Total += toolsBytes
+	add(DiskUsageStaleVersions, true, stale...)
+	add(DiskUsageNode, false, a.getNodeVersionsDir())
+	add(DiskUsageNpmCache, true, a.GetLocalCacheDir())
+	add(DiskUsageSkills, false, filepath.Join(ccDir, "skills"))
+	add(DiskUsageDownloads, true, a.downloadLeftovers()...)
+	add(DiskUsageBundles, false, filepath.Join(ccDir, "bundles"))
+	add(DiskUsageRuns, false, a.getRunsDir())
+
+	counted := map[string]bool{"tools": true, "node": true, "skills": true, "bundles": true, "runs": true}
+	var other []string
+	entries, _ := os.ReadDir(ccDir)
+	for _, e := range entries {
+		if !counted[e.Name()] && !strings.HasPrefix(e.Name(), ".import-") {
+			other = append(other, filepath.Join(ccDir, e.Name()))
+		}
+	}
+	add(DiskUsageOther, false, other...)
+
+	for _, name := range managedTools {
+		var dir string
+		if isPythonTool(name) {
+			dir = tm.pythonToolEnvDir(name)
+		} else if active := tm.loadVersionState(name).Active; active != "" {
+			dir = filepath.Join(tm.toolVersionsDir(name), active)
+		} else if pkg := tm.GetPackageName(name); pkg != "" {
+			dir = filepath.Join(npmGlobalModulesDir(tm.toolsDir()), pkg)
+		}
+		if _, err := os.Stat(dir); dir == "" || err != nil {
+			continue
+		}
+		usage.Tools = append(usage.Tools, ToolDiskUsage{Name: name, Bytes: pathSize(dir)})
+	}
+	return usage
+}
+
+// CleanDiskUsage removes the contents of the given cleanable categories and
+// returns the disk usage afterwards. Cleaning stale versions leaves nothing to
+// roll back to.
+func (a *App) CleanDiskUsage(categories []string) (DiskUsage, error) {
+	tm := NewToolManager(a)
+	var paths []string
+	for _, c := range categories {
+		switch c {
+		case DiskUsageStaleVersions:
+			paths = append(paths, tm.staleToolPaths()...)
+		case DiskUsageNpmCache:
+			paths = append(paths, a.GetLocalCacheDir())
+		case DiskUsageDownloads:
+			paths = append(paths, a.downloadLeftovers()...)
+		default:
+			return a.DiskUsage(), fmt.Errorf("%s cannot be cleaned", c)
+		}
+	}
+
+	var freed int64
+	var firstErr error
+	for _, p := range paths {
+		size := pathSize(p)
+		if err := os.RemoveAll(p); err != nil {
+			a.log(fmt.Sprintf("Failed to remove %s: %v", p, err))
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		freed += size
+	}
+
+	// Forget the removed versions, so RollbackTool does not offer them
+	for _, name := range managedTools {
+		state := tm.loadVersionState(name)
+		var kept []string
+		for _, v := range state.Installed {
+			if _, err := os.Stat(filepath.Join(tm.toolVersionsDir(name), v)); err == nil {
+				kept = append(kept, v)
+			}
+		}
+		if len(kept) != len(state.Installed) {
+			state.Installed = kept
+			tm.saveVersionState(name, state)
+		}
+	}
+
+	a.log(a.tr("Freed %.1f MB", float64(freed)/(1<<20)))
+	return a.DiskUsage(), firstErr
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FanOutTarget is one tool/provider pair of a fan-out launch.
+type FanOutTarget struct {
+	Tool     string `json:"tool"`
+	Provider string `json:"provider"`
+}
+
+// FanOutAgent is an agent started in its own git worktree.
+type FanOutAgent struct {
+	Id           string `json:"id"`
+	Tool         string `json:"tool"`
+	Provider     string `json:"provider"`
+	Branch       string `json:"branch"`
+	WorktreePath string `json:"worktree_path"`
+	Session      string `json:"session,omitempty"` // tmux/zellij session name when the project uses one
+	Error        string `json:"error,omitempty"`   // Set when the agent could not be started
+}
+
+// FanOutRun groups the agents started together on the same base commit.
+type FanOutRun struct {
+	Id         string        `json:"id"`
+	ProjectDir string        `json:"project_dir"`
+	BaseBranch string        `json:"base_branch"`
+	BaseCommit string        `json:"base_commit"`
+	CreatedAt  string        `json:"created_at"` // RFC3339
+	Agents     []FanOutAgent `json:"agents"`
+}
+
+// WorktreeDiff summarizes what an agent changed relative to the base commit,
+// including uncommitted and untracked files.
+type WorktreeDiff struct {
+	AgentId      string   `json:"agent_id"`
+	Tool         string   `json:"tool"`
+	Provider     string   `json:"provider"`
+	Branch       string   `json:"branch"`
+	Commits      int      `json:"commits"`
+	FilesChanged int      `json:"files_changed"`
+	Insertions   int      `json:"insertions"`
+	Deletions    int      `json:"deletions"`
+	Files        []string `json:"files"`
+	Error        string   `json:"error,omitempty"`
+}
+
+func (a *App) getFanOutDir() string {
+	return filepath.Join(a.GetUserHomeDir(), ".cceasy", "fanout")
+}
+
+func (a *App) getWorktreesDir() string {
+	return filepath.Join(a.GetUserHomeDir(), ".cceasy", "worktrees")
+}
+
+// getFanOutHomesDir returns the directory holding the config homes of the
+// agents of a run.
+func (a *App) getFanOutHomesDir(runId string) string {
+	return filepath.Join(a.getFanOutDir(), runId)
+}
+
+// configHomeVar is the environment variable that moves a tool's config
+// directory, and the directory in the home directory it replaces. State is a
+// file in the home directory that moves along with it.
+type configHomeVar struct {
+	name  string
+	dir   string
+	state string
+}
+
+// configHomeVars lists the tools that can be pointed at another config
+// directory. Other tools get another home directory instead.
+var configHomeVars = map[string]configHomeVar{
+	"claude": {name: "CLAUDE_CONFIG_DIR", dir: ".claude", state: ".claude.json"},
+	"codex":  {name: "CODEX_HOME", dir: ".codex"},
+}
+
+// isolateConfigHome points the plan's tool at configHome instead of the user's
+// config: its config files in the home directory are written there and the
+// environment tells the tool to read them from there.
+func (a *App) isolateConfigHome(plan *launchPlan, configHome string) {
+	home := a.GetUserHomeDir()
+	v, ok := configHomeVars[plan.toolName]
+	for i, c := range plan.configFiles {
+		rel, err := filepath.Rel(home, c.Path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		switch {
+		case !ok:
+			plan.configFiles[i].Path = filepath.Join(configHome, rel)
+		case rel == v.dir:
+			plan.configFiles[i].Path = configHome
+		case strings.HasPrefix(rel, v.dir+string(filepath.Separator)):
+			plan.configFiles[i].Path = filepath.Join(configHome, rel[len(v.dir)+1:])
+		case v.state != "" && rel == v.state:
+			plan.configFiles[i].Path = filepath.Join(configHome, v.state)
+		}
+	}
+	if ok {
+		plan.env[v.name] = configHome
+		return
+	}
+	plan.env["HOME"] = configHome
+	if runtime.GOOS == "windows" {
+		plan.env["USERPROFILE"] = configHome
+	}
+}
+
+// runGit runs git in dir and returns its trimmed output. The output is included
+// in the error so git's own message reaches the user.
+func runGit(dir string, args ...string) (string, error) {
+	cmd := createHiddenCmd("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v\nOutput: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (a *App) saveFanOutRun(run *FanOutRun) error {
+	dir := a.getFanOutDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, run.Id+".json"), data, 0644)
+}
+
+// fanOutRunId matches the ids LaunchFanOut generates, with or without the
+// microseconds older runs lack, so an id from the frontend cannot point outside
+// the fan-out directory.
+var fanOutRunId = regexp.MustCompile(`^[0-9]{8}-[0-9]{6}(-[0-9]{6})?$`)
+
+func (a *App) loadFanOutRun(id string) (*FanOutRun, error) {
+	if !fanOutRunId.MatchString(id) {
+		return nil, fmt.Errorf("invalid fan-out run id: %s", id)
+	}
+	data, err := os.ReadFile(filepath.Join(a.getFanOutDir(), id+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown fan-out run: %s", id)
+	}
+	var run FanOutRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, err
+	}
+	if run.Id != id {
+		return nil, fmt.Errorf("fan-out run %s has a mismatched id: %s", id, run.Id)
+	}
+	return &run, nil
+}
+
+// LaunchFanOut starts one agent per tool/provider pair on the current commit of
+// projectDir. Each agent gets its own branch and worktree so their changes can
+// be compared and one of them kept with KeepFanOutResult.
+func (a *App) LaunchFanOut(projectDir string, targets []FanOutTarget, yoloMode bool, useProxy bool) (FanOutRun, error) {
+	if len(targets) == 0 {
+		return FanOutRun{}, fmt.Errorf("no tool/provider pairs given")
+	}
+	if projectDir == "" {
+		projectDir = a.GetCurrentProjectPath()
+	}
+	root, err := runGit(projectDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return FanOutRun{}, fmt.Errorf("%s is not a git repository", projectDir)
+	}
+	baseCommit, err := runGit(root, "rev-parse", "HEAD")
+	if err != nil {
+		return FanOutRun{}, fmt.Errorf("the repository has no commits yet")
+	}
+	baseBranch, _ := runGit(root, "rev-parse", "--abbrev-ref", "HEAD")
+
+	now := time.Now()
+	run := FanOutRun{
+		Id:         fmt.Sprintf("%s-%06d", now.Format("20060102-150405"), now.Nanosecond()/1000),
+		ProjectDir: root,
+		BaseBranch: baseBranch,
+		BaseCommit: baseCommit,
+		CreatedAt:  now.Format(time.RFC3339),
+	}
+	a.log(fmt.Sprintf("Fan-out %s: %d agent(s) on %s (%s)", run.Id, len(targets), baseBranch, baseCommit[:7]))
+
+	used := make(map[string]int)
+	for _, t := range targets {
+		agentId := strings.Trim(sessionNameUnsafe.ReplaceAllString(strings.ToLower(t.Tool+"-"+t.Provider), "-"), "-")
+		if used[agentId]++; used[agentId] > 1 {
+			agentId += "-" + strconv.Itoa(used[agentId])
+		}
+		agent := FanOutAgent{
+			Id:           agentId,
+			Tool:         strings.ToLower(t.Tool),
+			Provider:     t.Provider,
+			Branch:       fmt.Sprintf("aicoder/%s/%s", run.Id, agentId),
+			WorktreePath: filepath.Join(a.getWorktreesDir(), run.Id, agentId),
+		}
+		configHome := filepath.Join(a.getFanOutHomesDir(run.Id), agentId)
+		if err := a.launchFanOutAgent(root, &agent, configHome, yoloMode, useProxy); err != nil {
+			a.log(fmt.Sprintf("Fan-out agent %s failed: %v", agentId, err))
+			agent.Error = err.Error()
+		}
+		run.Agents = append(run.Agents, agent)
+	}
+
+	if err := a.saveFanOutRun(&run); err != nil {
+		return run, fmt.Errorf("failed to save fan-out run: %v", err)
+	}
+	a.emitEvent("fanout-launched", run)
+	return run, nil
+}
+
+// launchFanOutAgent creates the agent's worktree and starts the tool in it. The
+// settings of the original project apply, while the tool, its project config
+// files and its sandbox use the worktree. The tool's config in the home
+// directory goes to configHome instead, so agents of the same tool can use
+// different providers; it starts with just the files AICoder writes.
+func (a *App) launchFanOutAgent(root string, agent *FanOutAgent, configHome string, yoloMode bool, useProxy bool) error {
+	if err := os.MkdirAll(filepath.Dir(agent.WorktreePath), 0755); err != nil {
+		return err
+	}
+	if _, err := runGit(root, "worktree", "add", "-b", agent.Branch, agent.WorktreePath, "HEAD"); err != nil {
+		return err
+	}
+
+	prep, err := a.buildLaunchPlanIn(agent.Tool, root, agent.WorktreePath, useProxy, agent.Provider)
+	if err != nil {
+		return err
+	}
+	agent.Provider = prep.model.ModelName
+	if err := os.MkdirAll(configHome, 0700); err != nil {
+		return err
+	}
+	a.isolateConfigHome(prep, configHome)
+	prep.persistEnv = false
+	if prep.sandbox != nil {
+		profile := prep.project.Sandbox
+		profile.ReadWrite = append(append([]string{}, profile.ReadWrite...), configHome)
+		if prep.sandbox, err = a.sandboxCommand(profile, prep.toolName, agent.WorktreePath); err != nil {
+			return err
+		}
+	}
+	if err := a.applyLaunchPlan(prep); err != nil {
+		return err
+	}
+
+	if prep.project != nil && (prep.project.LaunchMode == LaunchModeTmux || prep.project.LaunchMode == LaunchModeZellij) {
+		info, err := a.launchInSession(prep.project.LaunchMode, prep.binaryName, yoloMode, "", agent.WorktreePath, prep.env, prep.model.ModelId, prep.extraArgs, prep.sandbox)
+		if err != nil {
+			return err
+		}
+		agent.Session = info.Name
+		return nil
+	}
+	a.platformLaunch(prep.binaryName, yoloMode, false, "", agent.WorktreePath, prep.env, prep.model.ModelId, prep.extraArgs, prep.sandbox)
+	return nil
+}
+
+// ListFanOutRuns returns the fan-out runs that have not been resolved yet,
+// newest first.
+func (a *App) ListFanOutRuns() []FanOutRun {
+	runs := []FanOutRun{}
+	entries, err := os.ReadDir(a.getFanOutDir())
+	if err != nil {
+		return runs
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		run, err := a.loadFanOutRun(strings.TrimSuffix(e.Name(), ".json"))
+		if err == nil {
+			runs = append(runs, *run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].CreatedAt > runs[j].CreatedAt })
+	return runs
+}
+
+// GetFanOutDiffs returns a diff summary per agent of a fan-out run.
+func (a *App) GetFanOutDiffs(runId string) ([]WorktreeDiff, error) {
+	run, err := a.loadFanOutRun(runId)
+	if err != nil {
+		return nil, err
+	}
+	diffs := make([]WorktreeDiff, 0, len(run.Agents))
+	for _, agent := range run.Agents {
+		d := WorktreeDiff{AgentId: agent.Id, Tool: agent.Tool, Provider: agent.Provider, Branch: agent.Branch, Files: []string{}}
+		if agent.Error != "" {
+			d.Error = agent.Error
+		} else if err := worktreeDiff(agent.WorktreePath, run.BaseCommit, &d); err != nil {
+			d.Error = err.Error()
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
+
+// worktreeDiff fills d with the changes in dir since base. Untracked files are
+// counted as changed files with all their lines inserted.
+func worktreeDiff(dir string, base string, d *WorktreeDiff) error {
+	count, err := runGit(dir, "rev-list", "--count", base+"..HEAD")
+	if err != nil {
+		return err
+	}
+	d.Commits, _ = strconv.Atoi(count)
+
+	numstat, err := runGit(dir, "diff", "--numstat", base)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(numstat, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		added, _ := strconv.Atoi(fields[0]) // "-" for binary files
+		deleted, _ := strconv.Atoi(fields[1])
+		d.Insertions += added
+		d.Deletions += deleted
+		d.Files = append(d.Files, fields[2])
+	}
+
+	untracked, err := runGit(dir, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return err
+	}
+	for _, f := range strings.Split(untracked, "\n") {
+		if f == "" {
+			continue
+		}
+		if data, err := os.ReadFile(filepath.Join(dir, f)); err == nil && len(data) > 0 {
+			d.Insertions += strings.Count(string(data), "\n")
+			if data[len(data)-1] != '\n' {
+				d.Insertions++
+			}
+		}
+		d.Files = append(d.Files, f)
+	}
+	d.FilesChanged = len(d.Files)
+	return nil
+}
+
+// KeepFanOutResult commits any pending changes of the chosen agent, merges its
+// branch into the project's current branch and removes all worktrees and
+// branches of the run.
+func (a *App) KeepFanOutResult(runId string, agentId string) error {
+	run, err := a.loadFanOutRun(runId)
+	if err != nil {
+		return err
+	}
+	var chosen *FanOutAgent
+	for i := range run.Agents {
+		if run.Agents[i].Id == agentId {
+			chosen = &run.Agents[i]
+		}
+	}
+	if chosen == nil || chosen.Error != "" {
+		return fmt.Errorf("agent %s has no result in run %s", agentId, runId)
+	}
+
+	// The result is merged into whatever is checked out in the project, so it
+	// must still be the branch the run started from, without pending changes.
+	// Runs started on a detached HEAD can be merged into any branch.
+	branch, err := runGit(run.ProjectDir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return err
+	}
+	if branch == "HEAD" {
+		return fmt.Errorf("%s has no branch checked out. Check out the branch to merge into and try again", run.ProjectDir)
+	}
+	if run.BaseBranch != "HEAD" && branch != run.BaseBranch {
+		return fmt.Errorf("%s is on %s, but the fan-out started from %s. Check out %s and try again", run.ProjectDir, branch, run.BaseBranch, run.BaseBranch)
+	}
+	if pending, err := runGit(run.ProjectDir, "status", "--porcelain", "--untracked-files=no"); err != nil {
+		return err
+	} else if pending != "" {
+		return fmt.Errorf("%s has uncommitted changes. Commit or stash them and try again", run.ProjectDir)
+	}
+
+	status, err := runGit(chosen.WorktreePath, "status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if status != "" {
+		if _, err := runGit(chosen.WorktreePath, "add", "-A"); err != nil {
+			return err
+		}
+		msg := fmt.Sprintf("%s (%s) result from AICoder fan-out %s", chosen.Tool, chosen.Provider, run.Id)
+		if _, err := runGit(chosen.WorktreePath, "commit", "-m", msg); err != nil {
+			return err
+		}
+	}
+
+	if _, err := runGit(run.ProjectDir, "merge", "--no-ff", "-m", "Merge "+chosen.Branch, chosen.Branch); err != nil {
+		runGit(run.ProjectDir, "merge", "--abort")
+		return err
+	}
+	a.log(fmt.Sprintf("Fan-out %s: merged %s into %s", run.Id, chosen.Branch, run.BaseBranch))
+	return a.removeFanOutRun(run)
+}
+
+// DiscardFanOutRun removes all worktrees and branches of a run without merging.
+func (a *App) DiscardFanOutRun(runId string) error {
+	run, err := a.loadFanOutRun(runId)
+	if err != nil {
+		return err
+	}
+	return a.removeFanOutRun(run)
+}
+
+func (a *App) removeFanOutRun(run *FanOutRun) error {
+	var failed []string
+	for _, agent := range run.Agents {
+		if _, err := os.Stat(agent.WorktreePath); err == nil {
+			if _, err := runGit(run.ProjectDir, "worktree", "remove", "--force", agent.WorktreePath); err != nil {
+				failed = append(failed, err.Error())
+				continue
+			}
+		}
+		runGit(run.ProjectDir, "branch", "-D", agent.Branch)
+	}
+	runGit(run.ProjectDir, "worktree", "prune")
+	os.Remove(filepath.Join(a.getWorktreesDir(), run.Id))
+	os.RemoveAll(a.getFanOutHomesDir(run.Id))
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to remove some worktrees:\n%s", strings.Join(failed, "\n"))
+	}
+	os.Remove(filepath.Join(a.getFanOutDir(), run.Id+".json"))
+	a.log(fmt.Sprintf("Fan-out %s: removed %d worktree(s)", run.Id, len(run.Agents)))
+	return nil
+}
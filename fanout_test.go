@@ -0,0 +1,67 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsolateConfigHome(t *testing.T) {
+	home := t.TempDir()
+	a := &App{testHomeDir: home}
+	project := filepath.Join(home, "project")
+	configHome := filepath.Join(home, ".cceasy", "fanout", "run", "agent")
+
+	plan := &launchPlan{
+		toolName: "claude",
+		env:      map[string]string{},
+		configFiles: []ConfigFileChange{
+			{Path: filepath.Join(home, ".claude", "settings.json")},
+			{Path: filepath.Join(home, ".claude.json")},
+			{Path: filepath.Join(project, ".claude", "settings.local.json")},
+		},
+	}
+	a.isolateConfigHome(plan, configHome)
+	want := []string{
+		filepath.Join(configHome, "settings.json"),
+		filepath.Join(configHome, ".claude.json"),
+		filepath.Join(project, ".claude", "settings.local.json"),
+	}
+	for i, c := range plan.configFiles {
+		if c.Path != want[i] {
+			t.Errorf("config file %d = %s, want %s", i, c.Path, want[i])
+		}
+	}
+	if plan.env["CLAUDE_CONFIG_DIR"] != configHome || plan.env["HOME"] != "" {
+		t.Errorf("env = %v", plan.env)
+	}
+
+	plan = &launchPlan{
+		toolName:    "gemini",
+		env:         map[string]string{},
+		configFiles: []ConfigFileChange{{Path: filepath.Join(home, ".gemini", "settings.json")}},
+	}
+	a.isolateConfigHome(plan, configHome)
+	if got := plan.configFiles[0].Path; got != filepath.Join(configHome, ".gemini", "settings.json") {
+		t.Errorf("gemini settings = %s", got)
+	}
+	if plan.env["HOME"] != configHome {
+		t.Errorf("env = %v", plan.env)
+	}
+}
+
+func TestLoadFanOutRunRejectsInvalidIds(t *testing.T) {
+	a := &App{testHomeDir: t.TempDir()}
+	for _, id := range []string{"", "../../etc/passwd", "20240102-030405-000001/../x", "20240102-0304"} {
+		if _, err := a.loadFanOutRun(id); err == nil || !strings.Contains(err.Error(), "invalid fan-out run id") {
+			t.Errorf("loadFanOutRun(%q) = %v, want an invalid id error", id, err)
+		}
+	}
+	run := &FanOutRun{Id: "20240102-030405-000001"}
+	if err := a.saveFanOutRun(run); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := a.loadFanOutRun(run.Id); err != nil || got.Id != run.Id {
+		t.Errorf("loadFanOutRun(%q) = %v, %v", run.Id, got, err)
+	}
+}
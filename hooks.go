@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	goruntime "runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HookStagePreLaunch = "pre_launch"
+	HookStagePostExit  = "post_exit"
+
+	defaultHookTimeout = 5 * time.Minute
+	// exitFileEnvVar names the file the launch script writes the tool's exit
+	// code to. It is only set when post-exit hooks are configured.
+	exitFileEnvVar = "AICODER_EXIT_FILE"
+	// exitWatchLimit bounds how long AICoder waits for a launched tool to exit.
+	exitWatchLimit = 24 * time.Hour
+)
+
+// Hook is a user-defined shell command run before a tool is launched or after
+// it exits. Pre-launch hooks that fail abort the launch.
+type Hook struct {
+	Name           string `json:"name"`
+	Command        string `json:"command"`
+	Stage          string `json:"stage"` // "pre_launch" or "post_exit"
+	Disabled       bool   `json:"disabled,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"` // 0 means 5 minutes
+}
+
+// hookContext is exposed to hooks as AICODER_* environment variables.
+type hookContext struct {
+	tool       string
+	provider   string
+	modelId    string
+	projectDir string
+	exitCode   string // Only set for post-exit hooks
+}
+
+// collectHooks returns the enabled hooks of a stage, global hooks first.
+func collectHooks(config *AppConfig, proj *ProjectConfig, stage string) []Hook {
+	var hooks []Hook
+	all := append([]Hook{}, config.Hooks...)
+	if proj != nil {
+		all = append(all, proj.Hooks...)
+	}
+	for _, h := range all {
+		if h.Stage == stage && !h.Disabled && strings.TrimSpace(h.Command) != "" {
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks
+}
+
+func (hc hookContext) environ() []string {
+	env := append(os.Environ(),
+		"AICODER_TOOL="+hc.tool,
+		"AICODER_PROVIDER="+hc.provider,
+		"AICODER_MODEL_ID="+hc.modelId,
+		"AICODER_PROJECT_DIR="+hc.projectDir,
+		"AICODER_PROJECT_NAME="+filepath.Base(hc.projectDir),
+	)
+	if hc.exitCode != "" {
+		env = append(env, "AICODER_EXIT_CODE="+hc.exitCode)
+	}
+	return env
+}
+
+// runHook runs a hook with the system shell in the project directory and
+// streams its output to the env-log channel.
+func (a *App) runHook(h Hook, hc hookContext) error {
+	name := h.Name
+	if name == "" {
+		name = h.Stage
+	}
+	timeout := defaultHookTimeout
+	if h.TimeoutSeconds > 0 {
+		timeout = time.Duration(h.TimeoutSeconds) * time.Second
+	}
+
+	shell, flag := "sh", "-c"
+	if goruntime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := createHiddenCmd(shell, flag, h.Command)
+	cmd.Dir = hc.projectDir
+	cmd.Env = hc.environ()
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	// Background children may keep the output open after the hook exits
+	cmd.WaitDelay = time.Second
+	startProcessGroup(cmd)
+
+	a.log(fmt.Sprintf("[hook %s] %s", name, h.Command))
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("hook %s failed to start: %v", name, err)
+	}
+
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			a.log(fmt.Sprintf("[hook %s] %s", name, scanner.Text()))
+		}
+		io.Copy(io.Discard, pr)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	waitDone := make(chan error, 1)
+	go func() { waitDone <- cmd.Wait() }()
+
+	var err error
+	select {
+	case err = <-waitDone:
+	case <-ctx.Done():
+		killProcessGroup(cmd)
+		<-waitDone
+		err = fmt.Errorf("timed out after %v", timeout)
+	}
+	if errors.Is(err, exec.ErrWaitDelay) {
+		err = nil
+	}
+	pw.Close()
+	<-outputDone
+
+	if err != nil {
+		return fmt.Errorf("hook %s failed: %v", name, err)
+	}
+	return nil
+}
+
+// runPreLaunchHooks runs the pre-launch hooks in order and stops at the first
+// failure.
+func (a *App) runPreLaunchHooks(hooks []Hook, hc hookContext) error {
+	for _, h := range hooks {
+		if err := a.runHook(h, hc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runPostExitHooks runs every post-exit hook, logging failures.
+func (a *App) runPostExitHooks(hooks []Hook, hc hookContext) {
+	for _, h := range hooks {
+		if err := a.runHook(h, hc); err != nil {
+			a.log(err.Error())
+		}
+	}
+}
+
+// newExitFile returns a fresh path for a launch script to record the tool's
+// exit code in.
+func (a *App) newExitFile() (string, error) {
+	dir := filepath.Join(a.GetUserHomeDir(), ".cceasy", "hooks")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("exit_%d", time.Now().UnixNano())), nil
+}
+
+// watchExitFile waits in the background for the launch script to write the exit
+// file, then runs the post-exit hooks.
+func (a *App) watchExitFile(exitFile string, hooks []Hook, hc hookContext) {
+	go func() {
+		deadline := time.Now().Add(exitWatchLimit)
+		for time.Now().Before(deadline) {
+			time.Sleep(2 * time.Second)
+			data, err := os.ReadFile(exitFile)
+			if err != nil || len(strings.TrimSpace(string(data))) == 0 {
+				continue
+			}
+			os.Remove(exitFile)
+			code := strings.TrimSpace(string(data))
+			if _, err := strconv.Atoi(code); err != nil {
+				code = "-1"
+			}
+			hc.exitCode = code
+			a.log(fmt.Sprintf("%s exited with code %s, running %d post-exit hook(s)", hc.tool, code, len(hooks)))
+			a.runPostExitHooks(hooks, hc)
+			return
+		}
+		a.log(fmt.Sprintf("Stopped waiting for %s to exit; post-exit hooks were not run", hc.tool))
+	}()
+}
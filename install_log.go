@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxInstallLogs is how many install and update transcripts are kept in
+// ~/.cceasy/logs.
+const maxInstallLogs = 50
+
+// InstallLogLine is sent as a "tool-install-log" event for every line of an
+// install or update.
+type InstallLogLine struct {
+	Tool      string `json:"tool"`
+	Operation string `json:"operation"` // "install" or "update"
+	Phase     string `json:"phase"`     // "prepare", "download", "install", "retry", "verify", "done" or "failed"
+	Line      string `json:"line"`
+	Timestamp string `json:"timestamp"`
+}
+
+// installLog streams the output of one install or update as events and keeps
+// the full transcript in ~/.cceasy/logs.
+type installLog struct {
+	app       *App
+	tool      string
+	operation string
+	path      string
+	file      *os.File
+	mu        sync.Mutex
+}
+
+// startInstallLog opens the transcript of an install or update. Failing to
+// write it does not stop the operation; the events are sent regardless.
+func (tm *ToolManager) startInstallLog(tool, operation string) *installLog {
+	il := &installLog{app: tm.app, tool: tool, operation: operation}
+	dir := filepath.Join(tm.app.GetUserHomeDir(), ".cceasy", "logs")
+	if err := os.MkdirAll(dir, 0755); err == nil {
+		pruneInstallLogs(dir)
+		il.path = filepath.Join(dir, fmt.Sprintf("%s-%s-%s.log", tool, operation, time.Now().Format("20060102-150405.000")))
+		if f, err := os.Create(il.path); err == nil {
+			il.file = f
+		} else {
+			il.path = ""
+		}
+	}
+	il.line("prepare", fmt.Sprintf("Starting %s of %s", operation, tool))
+	return il
+}
+
+// pruneInstallLogs removes the oldest transcripts beyond maxInstallLogs, making
+// room for a new one.
+func pruneInstallLogs(dir string) {
+	logs, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	if len(logs) < maxInstallLogs {
+		return
+	}
+	modTime := func(p string) time.Time {
+		if info, err := os.Stat(p); err == nil {
+			return info.ModTime()
+		}
+		return time.Time{}
+	}
+	sort.Slice(logs, func(i, j int) bool { return modTime(logs[i]).Before(modTime(logs[j])) })
+	for _, p := range logs[:len(logs)-maxInstallLogs+1] {
+		os.Remove(p)
+	}
+}
+
+// line records one line of output or progress.
+func (il *installLog) line(phase, text string) {
+	now := time.Now()
+	il.mu.Lock()
+	if il.file != nil {
+		fmt.Fprintf(il.file, "%s [%s] %s\n", now.Format("15:04:05.000"), phase, text)
+	}
+	il.mu.Unlock()
+	il.app.emitEvent("tool-install-log", InstallLogLine{
+		Tool:      il.tool,
+		Operation: il.operation,
+		Phase:     phase,
+		Line:      text,
+		Timestamp: now.Format(time.RFC3339Nano),
+	})
+}
+
+// log records a progress message and shows it in the environment log as well.
+func (il *installLog) log(phase, message string) {
+	il.app.log(message)
+	il.line(phase, message)
+}
+
+// run runs cmd, streaming its combined output line by line, and returns the
+// output for checking afterwards.
+func (il *installLog) run(cmd *exec.Cmd, phase string) (string, error) {
+	il.log(phase, il.app.tr("Running installation: %s %s", cmd.Path, strings.Join(cmd.Args[1:], " ")))
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	var out bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r := bufio.NewReader(pr)
+		for {
+			text, err := r.ReadString('\n')
+			out.WriteString(text)
+			if text = strings.TrimRight(text, "\r\n"); text != "" {
+				il.line(phase, text)
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	err := cmd.Run()
+	pw.Close()
+	<-done
+	return out.String(), err
+}
+
+// failed returns the error of a failed step: the advice for a known failure
+// in its output, or the end of the output.
+func (il *installLog) failed(err error, output string) error {
+	if advice := diagnoseInstallFailure(il.tool, output); advice != "" {
+		return fmt.Errorf("failed to install %s: %s", il.tool, advice)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > 20 {
+		lines = lines[len(lines)-20:]
+	}
+	return fmt.Errorf("failed to install %s: %v\nOutput: %s", il.tool, err, strings.Join(lines, "\n"))
+}
+
+// finish closes the transcript. A failed operation's error gets the path of
+// the transcript appended.
+func (il *installLog) finish(err error) error {
+	if err != nil {
+		il.line("failed", err.Error())
+	} else {
+		il.line("done", fmt.Sprintf("%s of %s finished", il.operation, il.tool))
+	}
+	il.mu.Lock()
+	if il.file != nil {
+		il.file.Close()
+		il.file = nil
+	}
+	il.mu.Unlock()
+	if err != nil && il.path != "" {
+		return fmt.Errorf("%w\nFull log: %s", err, il.path)
+	}
+	return err
+}
+
+var (
+	npmRequiredNodeRe = regexp.MustCompile(`required: \{[^}]*node: '([^']+)'|Required: \{[^}]*"node":"([^"]+)"`)
+	npmCurrentNodeRe  = regexp.MustCompile(`current: \{[^}]*node: '([^']+)'|Actual: +\{[^}]*"node":"([^"]+)"`)
+	pipRequiresRe     = regexp.MustCompile(`[Rr]equires-[Pp]ython[ :]*'?([^'\s]+)`)
+)
+
+// firstGroup returns the first non-empty submatch.
+func firstGroup(re *regexp.Regexp, s string) string {
+	if m := re.FindStringSubmatch(s); m != nil {
+		for _, g := range m[1:] {
+			if g != "" {
+				return g
+			}
+		}
+	}
+	return ""
+}
+
+// diagnoseInstallFailure turns known npm and pip failures into advice, or
+// returns "".
+func diagnoseInstallFailure(tool, output string) string {
+	has := func(patterns ...string) bool {
+		for _, p := range patterns {
+			if strings.Contains(output, p) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case has("ENOTEMPTY", "EBUSY"):
+		return fmt.Sprintf("files of the current installation are in use. Close every running %s and try again", tool)
+	case has("EACCES", "EPERM", "Permission denied"):
+		return "permission denied while writing the installation. Make sure your user owns ~/.cceasy and ~/.cc, then try again"
+	case has("E404", "404 Not Found", "is not in this registry", "No matching distribution found", "No solution found when resolving"):
+		return "the package or the requested version was not found in the registry. Check the pinned version if the tool is pinned; mirrors can also lag behind the official registry"
+	case has("ETIMEDOUT", "ECONNRESET", "ECONNREFUSED", "ENOTFOUND", "EAI_AGAIN", "socket hang up", "Read timed out", "Failed to establish a new connection"):
+		return "the registry could not be reached. Check your network and proxy settings, or choose another npm registry in the settings"
+	case has("EBADENGINE", "ENOTSUP", "Unsupported engine"):
+		required, current := firstGroup(npmRequiredNodeRe, output), firstGroup(npmCurrentNodeRe, output)
+		if required == "" {
+			return "the tool does not support the installed Node.js version. Install a newer Node.js and try again"
+		}
+		if current == "" {
+			current = "an older version"
+		}
+		return fmt.Sprintf("%s needs Node.js %s, but Node.js %s is installed. Install a matching Node.js and try again", tool, required, current)
+	case has("requires a different Python", "Requires-Python", "requires-python"):
+		if required := firstGroup(pipRequiresRe, output); required != "" {
+			return fmt.Sprintf("%s needs Python %s. Install uv, which downloads a matching Python, or install that Python and try again", tool, required)
+		}
+		return fmt.Sprintf("%s does not support the installed Python version. Install uv, which downloads a matching Python, and try again", tool)
+	}
+	return ""
+}
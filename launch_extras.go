@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// envVarName matches the variable names launch scripts can export. Names are
+// written into the scripts unquoted, so nothing else may pass.
+var envVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// collectLaunchExtras merges the extra CLI arguments and environment variables
+// configured on the tool, the selected provider and the project, in that order.
+// Arguments are concatenated so the most specific layer comes last on the
+// command line; for environment variables a later layer overrides an earlier one.
+func collectLaunchExtras(toolCfg *ToolConfig, model *ModelConfig, proj *ProjectConfig) ([]string, []EnvVar) {
+	var args []string
+	var env []EnvVar
+	if toolCfg != nil {
+		args = append(args, toolCfg.ExtraArgs...)
+		env = append(env, toolCfg.ExtraEnv...)
+	}
+	if model != nil {
+		args = append(args, model.ExtraArgs...)
+		env = append(env, model.ExtraEnv...)
+	}
+	if proj != nil {
+		args = append(args, proj.ExtraArgs...)
+		env = append(env, proj.ExtraEnv...)
+	}
+	return args, env
+}
+
+// launchVariables returns the variables available for expansion in extra
+// arguments and environment values.
+func (a *App) launchVariables(toolName string, model *ModelConfig, projectDir string) map[string]string {
+	vars := map[string]string{
+		"HOME":         a.GetUserHomeDir(),
+		"PROJECT_DIR":  projectDir,
+		"PROJECT_NAME": filepath.Base(projectDir),
+		"TOOL":         strings.ToLower(toolName),
+	}
+	if model != nil {
+		vars["PROVIDER"] = model.ModelName
+		vars["MODEL_ID"] = model.ModelId
+	}
+	return vars
+}
+
+// expandLaunchValue expands a leading "~" and $VAR / ${VAR} references. Launch
+// variables are looked up first, then the launch environment built so far, and
+// finally AICoder's own environment.
+func expandLaunchValue(value string, vars map[string]string, env map[string]string) string {
+	if value == "~" || strings.HasPrefix(value, "~/") {
+		value = "${HOME}" + value[1:]
+	}
+	return os.Expand(value, func(name string) string {
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		if v, ok := env[name]; ok {
+			return v
+		}
+		return os.Getenv(name)
+	})
+}
+
+// applyLaunchExtras expands the extra arguments and merges the extra environment
+// into env. Entries without a valid variable name are skipped.
+func (a *App) applyLaunchExtras(extraArgs []string, extraEnv []EnvVar, vars map[string]string, env map[string]string) []string {
+	for _, e := range extraEnv {
+		name := strings.TrimSpace(e.Name)
+		if !envVarName.MatchString(name) {
+			a.log("Skipping invalid extra environment variable name: " + e.Name)
+			continue
+		}
+		env[name] = expandLaunchValue(e.Value, vars, env)
+	}
+
+	args := make([]string, 0, len(extraArgs))
+	for _, arg := range extraArgs {
+		args = append(args, expandLaunchValue(arg, vars, env))
+	}
+	return args
+}
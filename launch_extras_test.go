@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestApplyLaunchExtrasSkipsInvalidNames(t *testing.T) {
+	a := &App{}
+	env := map[string]string{}
+	extra := []EnvVar{
+		{Name: "GOOD_NAME", Value: "1"},
+		{Name: " _also_good2 ", Value: "2"},
+		{Name: "X;touch /tmp/pwned;Y", Value: "3"},
+		{Name: "A$(id)", Value: "4"},
+		{Name: "1LEADING_DIGIT", Value: "5"},
+		{Name: `B" & calc & set "C`, Value: "6"},
+		{Name: "", Value: "7"},
+	}
+	a.applyLaunchExtras(nil, extra, map[string]string{}, env)
+	if len(env) != 2 || env["GOOD_NAME"] != "1" || env["_also_good2"] != "2" {
+		t.Errorf("env = %v, want only GOOD_NAME and _also_good2", env)
+	}
+}
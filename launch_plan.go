@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ConfigFileChange is a tool configuration file that a launch writes or removes.
+type ConfigFileChange struct {
+	Path    string `json:"path"`
+	Content string `json:"content,omitempty"` // New content when writing
+	Remove  bool   `json:"remove,omitempty"`
+	IsDir   bool   `json:"is_dir,omitempty"` // Remove the whole directory
+	// Merge is set for JSON files that belong to the tool and that AICoder only
+	// adds keys to. It holds those keys as a JSON object; Content is the local
+	// file with them merged in. Other machines merge Merge into their own file.
+	Merge string `json:"merge,omitempty"`
+}
+
+// configFileSet collects the config file changes of a launch plan in order.
+type configFileSet struct {
+	changes []ConfigFileChange
+}
+
+func (s *configFileSet) write(path string, data []byte) {
+	s.changes = append(s.changes, ConfigFileChange{Path: path, Content: string(data)})
+}
+
+// merge records a JSON file that keeps its other keys: data is the local file
+// with owned merged in.
+func (s *configFileSet) merge(path string, data []byte, owned map[string]interface{}) error {
+	ownedJson, err := json.Marshal(owned)
+	if err != nil {
+		return err
+	}
+	s.changes = append(s.changes, ConfigFileChange{Path: path, Content: string(data), Merge: string(ownedJson)})
+	return nil
+}
+
+func (s *configFileSet) remove(path string) {
+	s.changes = append(s.changes, ConfigFileChange{Path: path, Remove: true})
+}
+
+func (s *configFileSet) removeAll(dir string) {
+	s.changes = append(s.changes, ConfigFileChange{Path: dir, Remove: true, IsDir: true})
+}
+
+// applyConfigFiles writes or removes the given config files. Files that already
+// have the planned content are left alone. Writes are serialized so launches of
+// different tools cannot interleave.
+func (a *App) applyConfigFiles(changes []ConfigFileChange) error {
+	a.configWriteMutex.Lock()
+	defer a.configWriteMutex.Unlock()
+
+	for _, c := range changes {
+		if c.Remove {
+			var err error
+			if c.IsDir {
+				err = os.RemoveAll(c.Path)
+			} else {
+				err = os.Remove(c.Path)
+			}
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if err == nil {
+				a.log("Removed " + c.Path)
+			}
+			continue
+		}
+
+		data := []byte(c.Content)
+		if existing, err := os.ReadFile(c.Path); err == nil && bytes.Equal(existing, data) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(c.Path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(c.Path, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %v", c.Path, err)
+		}
+	}
+	return nil
+}
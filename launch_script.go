@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// writeLaunchScript writes a shell launch script, which holds the provider keys,
+// to ~/.cceasy/launch where only the user can read it. The script removes
+// itself as soon as it starts running.
+func (a *App) writeLaunchScript(prefix, content string) (string, error) {
+	dir := filepath.Join(a.GetUserHomeDir(), ".cceasy", "launch")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to write launch script: %v", err)
+	}
+	os.Chmod(dir, 0700)
+	path := filepath.Join(dir, fmt.Sprintf("%s_%d.sh", prefix, time.Now().UnixNano()))
+	content = strings.Replace(content, "\n", "\nrm -f \"$0\"\n", 1)
+	if err := os.WriteFile(path, []byte(content), 0700); err != nil {
+		return "", fmt.Errorf("failed to write launch script: %v", err)
+	}
+	return path, nil
+}
+
+// toolLaunchArgs returns the command line arguments for a tool, including the
+// CodeBuddy model selection and the tool specific yolo flag.
+func toolLaunchArgs(binaryName string, yoloMode bool, modelId string) []string {
+	cmdArgs := []string{}
+	if binaryName == "codebuddy" && modelId != "" {
+		cmdArgs = append(cmdArgs, "--model", modelId)
+	}
+
+	if yoloMode {
+		switch binaryName {
+		case "claude":
+			cmdArgs = append(cmdArgs, "--dangerously-skip-permissions")
+		case "gemini":
+			cmdArgs = append(cmdArgs, "--yolo")
+		case "codex":
+			cmdArgs = append(cmdArgs, "--full-auto")
+		case "codebuddy":
+			cmdArgs = append(cmdArgs, "-y")
+		case "iflow":
+			cmdArgs = append(cmdArgs, "-y")
+		case "kode":
+			cmdArgs = append(cmdArgs, "--dangerously-skip-permissions")
+		case "aider":
+			cmdArgs = append(cmdArgs, "--yes-always")
+		case "qodercli", "qoder":
+			cmdArgs = append(cmdArgs, "--yolo")
+		}
+	}
+	return cmdArgs
+}
+
+// shellQuote quotes s for safe use as a single word in a POSIX shell script.
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// sortedEnvKeys returns the keys of env in a stable order so generated scripts
+// are reproducible.
+func sortedEnvKeys(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// buildShellLaunchScript renders the bash script used to start a tool on Linux
+// and macOS: change to the project, unset inherited provider variables, export
+// the provider environment, run the activation lines (e.g. a Python
+// environment), put the chosen Node.js and the private tools bin first in PATH
+// and run the tool, inside the sandbox command when one is given.
+func buildShellLaunchScript(projectDir string, env map[string]string, activation string, sandbox []string, toolPath string, args []string, pauseOnExit bool) string {
+	scriptContent := "#!/bin/bash\n"
+	scriptContent += fmt.Sprintf("cd %s\n", shellQuote(projectDir))
+	for _, k := range providerEnvVars {
+		if _, ok := env[k]; !ok {
+			scriptContent += fmt.Sprintf("unset %s\n", k)
+		}
+	}
+	for _, k := range sortedEnvKeys(env) {
+		if !isLocalOnlyEnvVar(k) {
+			scriptContent += fmt.Sprintf("export %s=%s\n", k, shellQuote(env[k]))
+		}
+	}
+
+	scriptContent += activation
+
+	// Add local node to PATH, preceded by the Node.js version chosen for the launch
+	home, _ := os.UserHomeDir()
+	localBin := filepath.Join(home, ".cceasy", "tools", "bin")
+	if nodeBin := env[nodeBinEnvVar]; nodeBin != "" {
+		scriptContent += fmt.Sprintf("export PATH=%s:%s:\"$PATH\"\n", shellQuote(nodeBin), shellQuote(localBin))
+	} else {
+		scriptContent += fmt.Sprintf("export PATH=%s:\"$PATH\"\n", shellQuote(localBin))
+	}
+
+	cmdLine := ""
+	for _, arg := range sandbox {
+		cmdLine += shellQuote(arg) + " "
+	}
+	cmdLine += shellQuote(toolPath)
+	for _, arg := range args {
+		cmdLine += " " + shellQuote(arg)
+	}
+	scriptContent += cmdLine + "\n"
+	if exitFile, ok := env[exitFileEnvVar]; ok {
+		scriptContent += "AICODER_EXIT_CODE=$?\n"
+		scriptContent += fmt.Sprintf("echo \"$AICODER_EXIT_CODE\" > %s\n", shellQuote(exitFile))
+	}
+	if pauseOnExit {
+		scriptContent += "echo 'Press Enter to close...'\nread\n"
+	}
+	return scriptContent
+}
+
+// buildChildEnv returns the environment for a tool process started directly by
+// AICoder: the current environment without any provider variables, the chosen
+// Node.js and the private tools directory first in PATH, and env applied on top.
+func buildChildEnv(env map[string]string) []string {
+	skip := make(map[string]bool)
+	for _, k := range providerEnvVars {
+		skip[strings.ToUpper(k)] = true
+	}
+	for k := range env {
+		skip[strings.ToUpper(k)] = true
+	}
+
+	home, _ := os.UserHomeDir()
+	localBin := filepath.Join(home, ".cceasy", "tools", "bin")
+	if runtime.GOOS == "windows" {
+		localBin = filepath.Join(home, ".cceasy", "tools")
+	}
+	if nodeBin := env[nodeBinEnvVar]; nodeBin != "" {
+		localBin = nodeBin + string(os.PathListSeparator) + localBin
+	}
+
+	result := []string{}
+	pathFound := false
+	for _, e := range os.Environ() {
+		k := e
+		if i := strings.Index(e, "="); i > 0 {
+			k = e[:i]
+		}
+		upper := strings.ToUpper(k)
+		if upper == "PATH" {
+			if _, overridden := env[k]; !overridden {
+				result = append(result, fmt.Sprintf("%s=%s%c%s", k, localBin, os.PathListSeparator, e[len(k)+1:]))
+				pathFound = true
+			}
+			continue
+		}
+		if skip[upper] {
+			continue
+		}
+		result = append(result, e)
+	}
+	if !pathFound {
+		if _, overridden := env["PATH"]; !overridden {
+			result = append(result, "PATH="+localBin)
+		}
+	}
+	for _, k := range sortedEnvKeys(env) {
+		if !isLocalOnlyEnvVar(k) {
+			result = append(result, k+"="+env[k])
+		}
+	}
+	return result
+}
+
+// terminalCommand is a command run by runInTerminal, optionally reading its
+// standard input from a file.
+type terminalCommand struct {
+	Args  []string
+	Stdin string
+}
+
+// buildTerminalCommandsScript renders the bash script used by runInTerminal on
+// Linux and macOS: run the commands in order until one fails, remove the
+// cleanup files, record the exit code and wait for Enter.
+func buildTerminalCommandsScript(cmds []terminalCommand, cleanup []string, exitFile string) string {
+	script := "#!/bin/bash\n"
+	script += "status=0\n"
+	for _, c := range cmds {
+		words := make([]string, len(c.Args))
+		for i, arg := range c.Args {
+			words[i] = shellQuote(arg)
+		}
+		line := strings.Join(words, " ")
+		if c.Stdin != "" {
+			line += " < " + shellQuote(c.Stdin)
+		}
+		script += fmt.Sprintf("if [ $status -eq 0 ]; then\n  %s\n  status=$?\nfi\n", line)
+	}
+	for _, f := range cleanup {
+		script += fmt.Sprintf("rm -f %s\n", shellQuote(f))
+	}
+	if exitFile != "" {
+		script += fmt.Sprintf("echo \"$status\" > %s\n", shellQuote(exitFile))
+	}
+	script += "echo 'Press Enter to close...'\nread\n"
+	return script
+}
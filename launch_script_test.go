@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteLaunchScript(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	home := t.TempDir()
+	a := &App{testHomeDir: home}
+	marker := filepath.Join(home, "ran")
+	path, err := a.writeLaunchScript("aicoder_test", "#!/bin/bash\nexport ANTHROPIC_AUTH_TOKEN='secret'\ntouch "+shellQuote(marker)+"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{path, filepath.Dir(path)} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0700 {
+			t.Errorf("%s has mode %v, want 0700", p, info.Mode().Perm())
+		}
+	}
+
+	if out, err := exec.Command("bash", path).CombinedOutput(); err != nil {
+		t.Fatalf("script failed: %v\n%s", err, out)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("script did not run: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("script was not removed: %v", err)
+	}
+}
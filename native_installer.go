@@ -0,0 +1,345 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ToolInstaller describes a tool distributed as release archives or single
+// binaries instead of an npm package. URL templates may use {version}, {os},
+// {arch} and {ext}. Downloads are verified against Checksums, ChecksumURL or
+// the asset digests of ReleaseURL, in that order; without any of them the
+// install goes ahead with a warning.
+type ToolInstaller struct {
+	Name        string            `json:"name"`
+	Binary      string            `json:"binary"`                 // Executable in the archive and the name it is installed as, without .exe
+	URL         string            `json:"url"`                    // Download URL template
+	LatestURL   string            `json:"latest_url"`             // Returns the latest release: a GitHub release JSON or the bare version
+	ChecksumURL string            `json:"checksum_url,omitempty"` // sha256sum style list for the release, optional
+	ReleaseURL  string            `json:"release_url,omitempty"`  // GitHub release JSON, whose assets carry SHA-256 digests, optional
+	Checksums   map[string]string `json:"checksums,omitempty"`    // SHA-256 by download file name, optional
+	OS          map[string]string `json:"os,omitempty"`           // GOOS to the name used in URLs
+	Arch        map[string]string `json:"arch,omitempty"`         // GOARCH to the name used in URLs
+	Ext         map[string]string `json:"ext,omitempty"`          // GOOS to "zip", "tar.gz" or "" for a bare binary
+}
+
+// builtinToolInstallers are the tools installed from their releases.
+var builtinToolInstallers = []ToolInstaller{
+	{
+		Name:       "opencode",
+		Binary:     "opencode",
+		URL:        "https://github.com/sst/opencode/releases/download/v{version}/opencode-{os}-{arch}.{ext}",
+		LatestURL:  "https://api.github.com/repos/sst/opencode/releases/latest",
+		ReleaseURL: "https://api.github.com/repos/sst/opencode/releases/tags/v{version}",
+		Arch:       map[string]string{"amd64": "x64", "arm64": "arm64"},
+		Ext:        map[string]string{"linux": "tar.gz", "darwin": "zip", "windows": "zip"},
+	},
+}
+
+// nativeInstaller returns the release installer of a tool, or nil for npm
+// tools. Installers in the config replace the built-in ones of the same name.
+func (tm *ToolManager) nativeInstaller(name string) *ToolInstaller {
+	if config, err := tm.app.LoadConfig(); err == nil {
+		for i := range config.ToolInstallers {
+			if config.ToolInstallers[i].Name == name {
+				return &config.ToolInstallers[i]
+			}
+		}
+	}
+	for i := range builtinToolInstallers {
+		if builtinToolInstallers[i].Name == name {
+			inst := builtinToolInstallers[i]
+			return &inst
+		}
+	}
+	return nil
+}
+
+// expand fills in a URL template for this machine.
+func (inst *ToolInstaller) expand(template, version string) string {
+	osName, arch, ext := runtime.GOOS, runtime.GOARCH, inst.Ext[runtime.GOOS]
+	if v, ok := inst.OS[osName]; ok {
+		osName = v
+	}
+	if v, ok := inst.Arch[arch]; ok {
+		arch = v
+	}
+	r := strings.NewReplacer("{version}", version, "{os}", osName, "{arch}", arch, "{ext}", ext)
+	return r.Replace(template)
+}
+
+// latestNativeVersion asks the release source for the latest version.
+func (tm *ToolManager) latestNativeVersion(inst *ToolInstaller) (string, error) {
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Get(inst.expand(inst.LatestURL, ""))
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch the latest %s release: %v", inst.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch the latest %s release: %s", inst.Name, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return "", err
+	}
+	version := strings.TrimSpace(string(data))
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if json.Unmarshal(data, &release) == nil && release.TagName != "" {
+		version = release.TagName
+	}
+	version = strings.TrimPrefix(version, "v")
+	if version == "" || strings.ContainsAny(version, "/\\ \n") {
+		return "", fmt.Errorf("unexpected %s release version: %q", inst.Name, version)
+	}
+	return version, nil
+}
+
+// latestToolVersion returns the version an update of the tool would install:
+// the newest npm, PyPI or release version matching the tool's pin.
+func (tm *ToolManager) latestToolVersion(name, npmPath string) (string, error) {
+	spec := tm.versionSpec(name)
+	if inst := tm.nativeInstaller(name); inst != nil {
+		if spec != "latest" {
+			return spec, nil
+		}
+		return tm.latestNativeVersion(inst)
+	}
+	if tool, ok := pythonTools[name]; ok {
+		if spec != "latest" {
+			return spec, nil
+		}
+		return latestPythonVersion(tool.Package)
+	}
+	if npmPath == "" {
+		return "", fmt.Errorf("npm not found")
+	}
+	return tm.app.getLatestNpmVersion(npmPath, tm.GetPackageName(name)+"@"+spec)
+}
+
+// expectedChecksum returns the SHA-256 a download must have, or "" when the
+// installer does not publish one.
+func (tm *ToolManager) expectedChecksum(inst *ToolInstaller, version, fileName string) (string, error) {
+	if sum := inst.Checksums[fileName]; sum != "" {
+		return strings.ToLower(sum), nil
+	}
+	if inst.ChecksumURL == "" {
+		if inst.ReleaseURL != "" {
+			return releaseAssetDigest(inst.expand(inst.ReleaseURL, version), fileName)
+		}
+		return "", nil
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(inst.expand(inst.ChecksumURL, version))
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch checksums: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch checksums: %s", resp.Status)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == fileName {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s", fileName)
+}
+
+// releaseAssetDigest returns the SHA-256 GitHub lists for an asset of a
+// release, or "" for releases published before GitHub kept digests.
+func releaseAssetDigest(releaseURL, fileName string) (string, error) {
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Get(releaseURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch checksums: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch checksums: %s", resp.Status)
+	}
+	var release struct {
+		Assets []struct {
+			Name   string `json:"name"`
+			Digest string `json:"digest"`
+		} `json:"assets"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 16<<20)).Decode(&release); err != nil {
+		return "", fmt.Errorf("failed to fetch checksums: %v", err)
+	}
+	for _, asset := range release.Assets {
+		if asset.Name == fileName {
+			if sum, ok := strings.CutPrefix(asset.Digest, "sha256:"); ok {
+				return strings.ToLower(sum), nil
+			}
+			return "", nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s", fileName)
+}
+
+// installNative downloads a release of a tool into its own version directory
+// and activates it, like an npm install.
+func (tm *ToolManager) installNative(inst *ToolInstaller, il *installLog) error {
+	version := tm.versionSpec(inst.Name)
+	if version == "latest" {
+		latest, err := tm.latestNativeVersion(inst)
+		if err != nil {
+			return err
+		}
+		version = latest
+	}
+	url := inst.expand(inst.URL, version)
+	fileName := url[strings.LastIndex(url, "/")+1:]
+
+	stagingDir := filepath.Join(tm.toolVersionsDir(inst.Name), fmt.Sprintf(".install-%d", time.Now().UnixNano()))
+	if err := os.MkdirAll(toolBinDir(stagingDir), 0755); err != nil {
+		return fmt.Errorf("failed to create install directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir) // Only left over when the installation failed
+
+	il.log("download", tm.app.tr("Downloading %s %s from %s...", inst.Name, version, url))
+	archivePath := filepath.Join(stagingDir, fileName)
+	if err := tm.app.downloadFile(archivePath, url); err != nil {
+		return fmt.Errorf("failed to download %s: %v", inst.Name, err)
+	}
+
+	expected, err := tm.expectedChecksum(inst, version, fileName)
+	if err != nil {
+		return err
+	}
+	if expected == "" {
+		il.log("verify", tm.app.tr("Warning: no checksum published for %s, the download of %s is not verified", fileName, inst.Name))
+	} else {
+		actual, err := fileSHA256(archivePath)
+		if err != nil {
+			return err
+		}
+		if actual != expected {
+			return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", fileName, expected, actual)
+		}
+	}
+
+	binary := inst.Binary
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	target := filepath.Join(toolBinDir(stagingDir), binary)
+	switch {
+	case strings.HasSuffix(fileName, ".zip"):
+		err = extractBinaryFromZip(archivePath, binary, target)
+	case strings.HasSuffix(fileName, ".tar.gz") || strings.HasSuffix(fileName, ".tgz"):
+		err = extractBinaryFromTarGz(archivePath, binary, target)
+	default:
+		err = os.Rename(archivePath, target)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to unpack %s: %v", fileName, err)
+	}
+	os.Remove(archivePath)
+	if err := os.Chmod(target, 0755); err != nil {
+		return err
+	}
+
+	if err := tm.commitToolVersion(inst.Name, stagingDir, version); err != nil {
+		return err
+	}
+	status := tm.GetToolStatus(inst.Name)
+	if !status.Installed {
+		return fmt.Errorf("installation completed but tool verification failed - %s not found", inst.Name)
+	}
+	il.log("verify", tm.app.tr("✓ %s installed and verified successfully (version: %s)", inst.Name, version))
+	return nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func writeBinary(r io.Reader, target string) error {
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// extractBinaryFromZip writes the file named binary, wherever it is in the
+// archive, to target.
+func extractBinaryFromZip(archive, binary, target string) error {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || filepath.Base(filepath.FromSlash(f.Name)) != binary {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		return writeBinary(rc, target)
+	}
+	return fmt.Errorf("%s not found in archive", binary)
+}
+
+// extractBinaryFromTarGz writes the file named binary, wherever it is in the
+// archive, to target.
+func extractBinaryFromTarGz(archive, binary, target string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return fmt.Errorf("%s not found in archive", binary)
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeReg && filepath.Base(filepath.FromSlash(header.Name)) == binary {
+			return writeBinary(tr, target)
+		}
+	}
+}
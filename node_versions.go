@@ -0,0 +1,340 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nodeBinEnvVar carries the bin directory of the Node.js version chosen for a
+// launch. The launch scripts put it first in PATH.
+const nodeBinEnvVar = "AICODER_NODE_BIN"
+
+// NodeVersionInfo is a Node.js version managed by AICoder under ~/.cceasy/node.
+type NodeVersionInfo struct {
+	Version string `json:"version"` // e.g. "20.11.1"
+	Path    string `json:"path"`
+	Bin     string `json:"bin"` // Directory holding the node executable
+	Lts     string `json:"lts"` // LTS codename, empty when unknown or not an LTS release
+}
+
+// nodeRelease is an entry of the Node.js dist index.json.
+type nodeRelease struct {
+	Version string          `json:"version"` // e.g. "v20.11.1"
+	Lts     json.RawMessage `json:"lts"`     // false or the codename
+}
+
+func (r nodeRelease) ltsName() string {
+	var name string
+	if json.Unmarshal(r.Lts, &name) == nil {
+		return strings.ToLower(name)
+	}
+	return ""
+}
+
+func (a *App) getNodeVersionsDir() string {
+	return filepath.Join(a.GetUserHomeDir(), ".cceasy", "node")
+}
+
+func nodeBinDir(dir string) string {
+	if runtime.GOOS == "windows" {
+		return dir
+	}
+	return filepath.Join(dir, "bin")
+}
+
+// ListNodeVersions returns the installed Node.js versions, newest first.
+func (a *App) ListNodeVersions() []NodeVersionInfo {
+	versions := []NodeVersionInfo{}
+	entries, err := os.ReadDir(a.getNodeVersionsDir())
+	if err != nil {
+		return versions
+	}
+	lts := make(map[string]string)
+	for _, r := range a.cachedNodeIndex() {
+		lts[strings.TrimPrefix(r.Version, "v")] = r.ltsName()
+	}
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), "v") {
+			continue
+		}
+		dir := filepath.Join(a.getNodeVersionsDir(), e.Name())
+		exe := "node"
+		if runtime.GOOS == "windows" {
+			exe = "node.exe"
+		}
+		if _, err := os.Stat(filepath.Join(nodeBinDir(dir), exe)); err != nil {
+			continue
+		}
+		version := strings.TrimPrefix(e.Name(), "v")
+		versions = append(versions, NodeVersionInfo{Version: version, Path: dir, Bin: nodeBinDir(dir), Lts: lts[version]})
+	}
+	sort.Slice(versions, func(i, j int) bool { return compareNodeVersions(versions[i].Version, versions[j].Version) > 0 })
+	return versions
+}
+
+// ListAvailableNodeVersions returns the newest release of every Node.js major
+// version from 18 on, newest first.
+func (a *App) ListAvailableNodeVersions() ([]NodeVersionInfo, error) {
+	index, err := a.fetchNodeIndex()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	versions := []NodeVersionInfo{}
+	for _, r := range index {
+		version := strings.TrimPrefix(r.Version, "v")
+		major := strings.SplitN(version, ".", 2)[0]
+		if n, err := strconv.Atoi(major); err != nil || n < 18 || seen[major] {
+			continue
+		}
+		seen[major] = true
+		versions = append(versions, NodeVersionInfo{Version: version, Lts: r.ltsName()})
+	}
+	return versions, nil
+}
+
+// InstallNodeVersion downloads a Node.js version into ~/.cceasy/node. The spec
+// may be a full version, a major or major.minor prefix, "lts/*", "lts/<name>"
+// or "node" for the latest release.
+func (a *App) InstallNodeVersion(spec string) (NodeVersionInfo, error) {
+	index, err := a.fetchNodeIndex()
+	if err != nil {
+		return NodeVersionInfo{}, err
+	}
+	versions := make([]string, 0, len(index))
+	lts := make(map[string]string)
+	for _, r := range index {
+		v := strings.TrimPrefix(r.Version, "v")
+		versions = append(versions, v)
+		lts[v] = r.ltsName()
+	}
+	version := matchNodeVersion(spec, versions, lts)
+	if version == "" {
+		return NodeVersionInfo{}, fmt.Errorf("no Node.js release matches %q", spec)
+	}
+
+	a.nodeVersionMutex.Lock()
+	defer a.nodeVersionMutex.Unlock()
+
+	dir := filepath.Join(a.getNodeVersionsDir(), "v"+version)
+	info := NodeVersionInfo{Version: version, Path: dir, Bin: nodeBinDir(dir), Lts: lts[version]}
+	if _, err := os.Stat(dir); err == nil {
+		return info, nil
+	}
+	if err := a.downloadNodeVersion(version, dir); err != nil {
+		os.RemoveAll(dir)
+		return NodeVersionInfo{}, err
+	}
+	a.log(a.tr("Node.js %s installed to %s", version, dir))
+	return info, nil
+}
+
+// RemoveNodeVersion deletes an installed Node.js version.
+func (a *App) RemoveNodeVersion(version string) error {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if version == "" || strings.ContainsAny(version, `/\`) {
+		return fmt.Errorf("invalid Node.js version: %q", version)
+	}
+	dir := filepath.Join(a.getNodeVersionsDir(), "v"+version)
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("Node.js %s is not installed", version)
+	}
+	return os.RemoveAll(dir)
+}
+
+func (a *App) downloadNodeVersion(version, targetDir string) error {
+	arch := "x64"
+	if runtime.GOARCH == "arm64" {
+		arch = "arm64"
+	}
+	platform := runtime.GOOS
+	ext := "tar.gz"
+	if platform == "windows" {
+		platform = "win"
+		ext = "zip"
+	}
+	baseName := fmt.Sprintf("node-v%s-%s-%s", version, platform, arch)
+	fileName := baseName + "." + ext
+	url := fmt.Sprintf("%s/v%s/%s", a.nodeDistURL(), version, fileName)
+
+	a.log(a.tr("Downloading Node.js from %s...", url))
+	archivePath := filepath.Join(os.TempDir(), fileName)
+	if err := a.downloadFile(archivePath, url); err != nil {
+		return err
+	}
+	defer os.Remove(archivePath)
+
+	a.log(a.tr("Extracting Node.js..."))
+	parent := filepath.Dir(targetDir)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return err
+	}
+	if ext == "zip" {
+		// The archive holds a single node-v<version>-win-<arch> directory
+		if err := a.unzip(archivePath, parent); err != nil {
+			return err
+		}
+		return os.Rename(filepath.Join(parent, baseName), targetDir)
+	}
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return err
+	}
+	cmd := exec.Command("tar", "-xzf", archivePath, "--strip-components=1", "-C", targetDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("tar failed: %s\n%s", err, string(output))
+	}
+	return nil
+}
+
+func (a *App) nodeDistURL() string {
+	if strings.HasPrefix(strings.ToLower(a.CurrentLanguage), "zh") {
+		return "https://mirrors.tuna.tsinghua.edu.cn/nodejs-release"
+	}
+	return "https://nodejs.org/dist"
+}
+
+// fetchNodeIndex downloads the list of Node.js releases (newest first) and
+// keeps a copy so LTS aliases can be resolved offline at launch.
+func (a *App) fetchNodeIndex() ([]nodeRelease, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(a.nodeDistURL() + "/index.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Node.js releases: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch Node.js releases: %s", resp.Status)
+	}
+	var index []nodeRelease
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return nil, fmt.Errorf("invalid Node.js release index: %v", err)
+	}
+	if data, err := json.Marshal(index); err == nil {
+		os.MkdirAll(a.getNodeVersionsDir(), 0755)
+		os.WriteFile(filepath.Join(a.getNodeVersionsDir(), "index.json"), data, 0644)
+	}
+	return index, nil
+}
+
+func (a *App) cachedNodeIndex() []nodeRelease {
+	var index []nodeRelease
+	if data, err := os.ReadFile(filepath.Join(a.getNodeVersionsDir(), "index.json")); err == nil {
+		json.Unmarshal(data, &index)
+	}
+	return index
+}
+
+// matchNodeVersion returns the newest of versions matching spec, or "" when
+// none does. lts maps versions to their LTS codename.
+func matchNodeVersion(spec string, versions []string, lts map[string]string) string {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	best := ""
+	for _, v := range versions {
+		var ok bool
+		switch {
+		case spec == "node" || spec == "latest" || spec == "current":
+			ok = true
+		case spec == "lts/*" || spec == "lts":
+			ok = lts[v] != ""
+		case strings.HasPrefix(spec, "lts/"):
+			ok = lts[v] == strings.TrimPrefix(spec, "lts/")
+		default:
+			prefix := strings.TrimPrefix(spec, "v")
+			ok = prefix != "" && (v == prefix || strings.HasPrefix(v, prefix+"."))
+		}
+		if ok && (best == "" || compareNodeVersions(v, best) > 0) {
+			best = v
+		}
+	}
+	return best
+}
+
+// compareNodeVersions compares two dotted versions numerically.
+func compareNodeVersions(x, y string) int {
+	xs := strings.Split(x, ".")
+	ys := strings.Split(y, ".")
+	for i := 0; i < len(xs) || i < len(ys); i++ {
+		var xn, yn int
+		if i < len(xs) {
+			xn, _ = strconv.Atoi(xs[i])
+		}
+		if i < len(ys) {
+			yn, _ = strconv.Atoi(ys[i])
+		}
+		if xn != yn {
+			if xn < yn {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// projectNodeVersionFile returns the version in the project's .nvmrc or
+// .node-version and the file it came from.
+func projectNodeVersionFile(projectDir string) (string, string) {
+	if projectDir == "" {
+		return "", ""
+	}
+	for _, name := range []string{".nvmrc", ".node-version"} {
+		data, err := os.ReadFile(filepath.Join(projectDir, name))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if i := strings.Index(line, "#"); i >= 0 {
+				line = strings.TrimSpace(line[:i])
+			}
+			if line != "" {
+				return line, name
+			}
+		}
+	}
+	return "", ""
+}
+
+// launchNodeVersion returns the Node.js version spec for launching a tool in a
+// project and where it came from. The project setting wins over the project's
+// .nvmrc/.node-version, which wins over the tool setting. An empty spec means
+// the default Node.js.
+func launchNodeVersion(toolCfg *ToolConfig, proj *ProjectConfig, projectDir string) (string, string) {
+	if proj != nil && proj.NodeVersion != "" {
+		return proj.NodeVersion, "project"
+	}
+	if spec, file := projectNodeVersionFile(projectDir); spec != "" {
+		return spec, file
+	}
+	if toolCfg != nil && toolCfg.NodeVersion != "" {
+		return toolCfg.NodeVersion, "tool"
+	}
+	return "", ""
+}
+
+// findNodeVersion returns the installed version matching spec, if any.
+func (a *App) findNodeVersion(spec string) *NodeVersionInfo {
+	installed := a.ListNodeVersions()
+	versions := make([]string, 0, len(installed))
+	lts := make(map[string]string)
+	for _, v := range installed {
+		versions = append(versions, v.Version)
+		lts[v.Version] = v.Lts
+	}
+	match := matchNodeVersion(spec, versions, lts)
+	for _, v := range installed {
+		if v.Version == match {
+			return &v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	NpmRegistryAuto      = "auto" // Fastest of the official registry and npmmirror, unless ~/.npmrc sets one
+	NpmRegistryOfficial  = "official"
+	NpmRegistryNpmmirror = "npmmirror"
+	NpmRegistryCustom    = "custom"
+	NpmRegistryNpmrc     = "npmrc" // Whatever the user's .npmrc says
+
+	officialNpmRegistry  = "https://registry.npmjs.org/"
+	npmmirrorNpmRegistry = "https://registry.npmmirror.com/"
+)
+
+// NpmRegistryLatency is the result of probing one registry.
+type NpmRegistryLatency struct {
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error"`
+}
+
+// The registry auto mode picked, measured once per run.
+var (
+	autoNpmRegistry      string
+	autoNpmRegistryMutex sync.Mutex
+)
+
+// npmrcMutex serializes writes of the private npmrc by parallel installs.
+var npmrcMutex sync.Mutex
+
+// userNpmrcRegistry returns the registry set in the user's ~/.npmrc, if any.
+func (a *App) userNpmrcRegistry() string {
+	f, err := os.Open(filepath.Join(a.GetUserHomeDir(), ".npmrc"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if ok && strings.TrimSpace(key) == "registry" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// resolveNpmRegistry returns the registry npm should use, or "" to leave the
+// choice to npm's own configuration.
+func (a *App) resolveNpmRegistry(config *AppConfig) string {
+	switch config.NpmRegistry {
+	case NpmRegistryOfficial:
+		return officialNpmRegistry
+	case NpmRegistryNpmmirror:
+		return npmmirrorNpmRegistry
+	case NpmRegistryCustom:
+		// Without a URL the custom registry falls back to auto
+		if config.NpmRegistryURL != "" {
+			return config.NpmRegistryURL
+		}
+	case NpmRegistryNpmrc:
+		return ""
+	}
+	if a.userNpmrcRegistry() != "" {
+		return ""
+	}
+
+	autoNpmRegistryMutex.Lock()
+	defer autoNpmRegistryMutex.Unlock()
+	if autoNpmRegistry == "" {
+		autoNpmRegistry = a.fastestNpmRegistry()
+	}
+	return autoNpmRegistry
+}
+
+// fastestNpmRegistry probes the official registry and npmmirror. When neither
+// answers, the mirror is used for Chinese and the official registry otherwise.
+func (a *App) fastestNpmRegistry() string {
+	best := NpmRegistryLatency{}
+	for _, r := range probeNpmRegistries([]NpmRegistryLatency{
+		{Name: NpmRegistryOfficial, URL: officialNpmRegistry},
+		{Name: NpmRegistryNpmmirror, URL: npmmirrorNpmRegistry},
+	}) {
+		if r.Error == "" && (best.URL == "" || r.LatencyMs < best.LatencyMs) {
+			best = r
+		}
+	}
+	if best.URL != "" {
+		a.log(a.tr("Using npm registry %s (%d ms)", best.URL, best.LatencyMs))
+		return best.URL
+	}
+	if strings.HasPrefix(strings.ToLower(a.CurrentLanguage), "zh") {
+		return npmmirrorNpmRegistry
+	}
+	return officialNpmRegistry
+}
+
+// probeNpmRegistries measures the latency of the registries in parallel.
+func probeNpmRegistries(registries []NpmRegistryLatency) []NpmRegistryLatency {
+	client := &http.Client{Timeout: 5 * time.Second}
+	var wg sync.WaitGroup
+	for i := range registries {
+		wg.Add(1)
+		go func(r *NpmRegistryLatency) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(r.URL, "/")+"/-/ping", nil)
+			if err != nil {
+				r.Error = err.Error()
+				return
+			}
+			start := time.Now()
+			resp, err := client.Do(req)
+			if err != nil {
+				r.Error = err.Error()
+				return
+			}
+			resp.Body.Close()
+			r.LatencyMs = time.Since(start).Milliseconds()
+			if resp.StatusCode >= 500 {
+				r.Error = resp.Status
+			}
+		}(&registries[i])
+	}
+	wg.Wait()
+	return registries
+}
+
+// MeasureNpmRegistries probes the official registry, npmmirror and the custom
+// registry if one is configured, for showing in the settings.
+func (a *App) MeasureNpmRegistries() []NpmRegistryLatency {
+	registries := []NpmRegistryLatency{
+		{Name: NpmRegistryOfficial, URL: officialNpmRegistry},
+		{Name: NpmRegistryNpmmirror, URL: npmmirrorNpmRegistry},
+	}
+	if config, err := a.LoadConfig(); err == nil && config.NpmRegistryURL != "" {
+		registries = append(registries, NpmRegistryLatency{Name: NpmRegistryCustom, URL: config.NpmRegistryURL})
+	}
+	return probeNpmRegistries(registries)
+}
+
+// npmRegistryArgs returns the npm arguments that select the configured
+// registry. With withAuth, the token of a custom registry goes into a private
+// copy of the user's ~/.npmrc, so it never shows up on a command line; without
+// it (remote hosts) the token is not used.
+func (a *App) npmRegistryArgs(withAuth bool) []string {
+	config, err := a.LoadConfig()
+	if err != nil {
+		return nil
+	}
+	registry := a.resolveNpmRegistry(&config)
+	if registry == "" {
+		return nil
+	}
+	args := []string{"--registry=" + registry}
+	if !withAuth || config.NpmRegistry != NpmRegistryCustom || config.NpmRegistryURL == "" || config.NpmRegistryToken == "" {
+		return args
+	}
+
+	u, err := url.Parse(registry)
+	if err != nil || u.Host == "" {
+		a.log("Invalid npm registry URL: " + registry)
+		return args
+	}
+	path := u.Path
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	content, _ := os.ReadFile(filepath.Join(a.GetUserHomeDir(), ".npmrc"))
+	npmrc := string(content)
+	if npmrc != "" && !strings.HasSuffix(npmrc, "\n") {
+		npmrc += "\n"
+	}
+	npmrc += "//" + u.Host + path + ":_authToken=" + config.NpmRegistryToken + "\n"
+
+	// npm may be reading the file for another install, so it is only replaced
+	// when it changed, and never rewritten in place
+	userconfig := filepath.Join(a.GetUserHomeDir(), ".cceasy", "npmrc")
+	npmrcMutex.Lock()
+	defer npmrcMutex.Unlock()
+	if current, err := os.ReadFile(userconfig); err == nil && string(current) == npmrc {
+		return append(args, "--userconfig", userconfig)
+	}
+	if err := os.MkdirAll(filepath.Dir(userconfig), 0755); err != nil {
+		return args
+	}
+	tmp := fmt.Sprintf("%s.tmp-%d", userconfig, time.Now().UnixNano())
+	if err := os.WriteFile(tmp, []byte(npmrc), 0600); err != nil {
+		a.log("Failed to write npm auth config: " + err.Error())
+		return args
+	}
+	if err := os.Rename(tmp, userconfig); err != nil {
+		os.Remove(tmp)
+		a.log("Failed to write npm auth config: " + err.Error())
+		return args
+	}
+	return append(args, "--userconfig", userconfig)
+}
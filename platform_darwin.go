@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"syscall"
 	"time"
 
 	wails_runtime "github.com/wailsapp/wails/v2/pkg/runtime"
@@ -221,7 +222,7 @@ func (a *App) installToolsInBackground() {
 			
 			// Check for updates
 			a.log(a.tr("Background: Checking for %s updates...", tool))
-			latest, err := a.getLatestNpmVersion(npmPath, tm.GetPackageName(tool))
+			latest, err := tm.latestToolVersion(tool, npmPath)
 			if err == nil && latest != "" && latest != status.Version {
 				a.log(a.tr("Background: New version available for %s: %s (current: %s). Updating...", tool, latest, status.Version))
 				if err := tm.UpdateTool(tool); err != nil {
@@ -333,7 +334,7 @@ func (a *App) GetDownloadsFolder() (string, error) {
 	return filepath.Join(home, "Downloads"), nil
 }
 
-func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, pythonEnv string, projectDir string, env map[string]string, modelId string) {
+func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, pythonEnv string, projectDir string, env map[string]string, modelId string, extraArgs []string, sandbox []string) {
 	tm := NewToolManager(a)
 	status := tm.GetToolStatus(binaryName)
 	if !status.Installed {
@@ -343,9 +344,9 @@ func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, p
 		// Emit event to show installation progress dialog
 		wails_runtime.EventsEmit(a.ctx, "tool-repair-start", binaryName)
 
-		// Check if npm is available first
-		npmPath := tm.getNpmPath()
-		if npmPath == "" {
+		// Check if npm is available first; Python tools and release binaries
+		// are installed without it
+		if tm.usesNpm(binaryName) && tm.getNpmPath() == "" {
 			wails_runtime.EventsEmit(a.ctx, "tool-repair-failed", binaryName, a.tr("npm not found. Please run environment check first."))
 			a.ShowMessage(a.tr("Installation Error"), a.tr("npm not found. Please run environment check first."))
 			return
@@ -371,50 +372,38 @@ func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, p
 		a.log(fmt.Sprintf("Tool %s installed successfully. Version: %s", binaryName, status.Version))
 	}
 	
-	cmdArgs := []string{}
-	if binaryName == "codebuddy" && modelId != "" {
-		cmdArgs = append(cmdArgs, "--model", modelId)
-	}
+	cmdArgs := append(toolLaunchArgs(binaryName, yoloMode, modelId), extraArgs...)
 
-	if yoloMode {
-		switch binaryName {
-		case "claude":
-			cmdArgs = append(cmdArgs, "--dangerously-skip-permissions")
-		case "gemini":
-			cmdArgs = append(cmdArgs, "--yolo")
-		case "codex":
-			cmdArgs = append(cmdArgs, "--full-auto")
-		case "codebuddy":
-			cmdArgs = append(cmdArgs, "-y")
-		case "iflow":
-			cmdArgs = append(cmdArgs, "-y")
-		case "kode":
-			cmdArgs = append(cmdArgs, "--dangerously-skip-permissions")
-		case "qodercli", "qoder":
-			cmdArgs = append(cmdArgs, "--yolo")
-		}
-	}
-	
-	scriptPath := filepath.Join(os.TempDir(), fmt.Sprintf("aicoder_launch_%d.sh", time.Now().UnixNano()))
-	scriptContent := "#!/bin/bash\n"
-	scriptContent += fmt.Sprintf("cd \"%s\"\n", projectDir)
-	for k, v := range env {
-		scriptContent += fmt.Sprintf("export %s=\"%s\"\n", k, v)
+	// Create shell script wrapper
+	scriptContent := buildShellLaunchScript(projectDir, env, a.pythonActivationScript(pythonEnv, projectDir), sandbox, status.Path, cmdArgs, false)
+	scriptPath, err := a.writeLaunchScript("aicoder_launch", scriptContent)
+	if err != nil {
+		a.log(err.Error())
+		return
 	}
 	
-	home, _ := os.UserHomeDir()
-	localBin := filepath.Join(home, ".cceasy", "tools", "bin")
-	scriptContent += fmt.Sprintf("export PATH=\"%s:$PATH\"\n", localBin)
-	
-	scriptContent += fmt.Sprintf("\"%s\" %s\n", status.Path, strings.Join(cmdArgs, " "))
-	
-	os.WriteFile(scriptPath, []byte(scriptContent), 0755)
-	
 	cmd := exec.Command("open", "-a", "Terminal", scriptPath)
-	cmd.Start()
+	if err := cmd.Start(); err != nil {
+		os.Remove(scriptPath)
+		a.log("Error launching terminal: " + err.Error())
+	}
+}
+
+// runInTerminal opens Terminal and runs the commands in it one after another
+// (see buildTerminalCommandsScript).
+func (a *App) runInTerminal(cmds []terminalCommand, cleanup []string, exitFile, title string) error {
+	scriptPath, err := a.writeLaunchScript("aicoder_run", buildTerminalCommandsScript(cmds, cleanup, exitFile))
+	if err != nil {
+		return err
+	}
+	if err := exec.Command("open", "-a", "Terminal", scriptPath).Start(); err != nil {
+		os.Remove(scriptPath)
+		return err
+	}
+	return nil
 }
 
-func (a *App) syncToSystemEnv(config AppConfig) {
+func (a *App) syncToSystemEnv(plan *launchPlan) {
 }
 
 func (a *App) LaunchInstallerAndExit(installerPath string) error {
@@ -454,3 +443,22 @@ func getWindowsVersionHidden() string {
 func createHiddenCmd(name string, args ...string) *exec.Cmd {
     return exec.Command(name, args...)
 }
+
+// startProcessGroup makes cmd the leader of a new process group, so that
+// killProcessGroup also stops the children it leaves behind.
+func startProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// killProcessGroup kills a command started with startProcessGroup and every
+// process in its group.
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	cmd.Process.Kill()
+}
@@ -519,7 +519,7 @@ func (a *App) installToolsInBackground() {
 
 			// Check for updates
 			a.log(a.tr("Background: Checking for %s updates...", tool))
-			latest, err := a.getLatestNpmVersion(npmExec, tm.GetPackageName(tool))
+			latest, err := tm.latestToolVersion(tool, npmExec)
 			if err == nil && latest != "" {
 				needsUpdate := a.compareVersions(status.Version, latest) < 0
 				if needsUpdate {
@@ -1353,7 +1353,7 @@ func (a *App) findSh() string {
 	return "sh"
 }
 
-func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, pythonEnv string, projectDir string, env map[string]string, modelId string) {
+func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, pythonEnv string, projectDir string, env map[string]string, modelId string, extraArgs []string, sandbox []string) {
 	tm := NewToolManager(a)
 	a.log(fmt.Sprintf("platformLaunch: Looking for tool '%s'", binaryName))
 	status := tm.GetToolStatus(binaryName)
@@ -1372,9 +1372,9 @@ func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, p
 		// Emit event to show installation progress dialog
 		runtime.EventsEmit(a.ctx, "tool-repair-start", binaryName)
 
-		// Check if npm is available first
-		npmPath := tm.getNpmPath()
-		if npmPath == "" {
+		// Check if npm is available first; Python tools and release binaries
+		// are installed without it
+		if tm.usesNpm(binaryName) && tm.getNpmPath() == "" {
 			runtime.EventsEmit(a.ctx, "tool-repair-failed", binaryName, a.tr("npm not found. Please run environment check first."))
 			a.ShowMessage(a.tr("Installation Error"), a.tr("npm not found. Please run environment check first."))
 			return
@@ -1427,6 +1427,8 @@ func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, p
 			flag = ""
 		case "kode":
 			flag = "--dangerously-skip-permissions"
+		case "aider":
+			flag = "--yes-always"
 		case "qodercli", "qoder":
 			flag = "--yolo"
 		}
@@ -1434,13 +1436,19 @@ func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, p
 			cmdArgs += " " + flag
 		}
 	}
+	for _, arg := range extraArgs {
+		cmdArgs += " " + quoteBatchArg(arg)
+	}
 
 	batchContent := "@echo off\r\n"
 	batchContent += "chcp 65001 > nul\r\n"
 	batchContent += fmt.Sprintf("cd /d \"%s\"\r\n", projectDir)
 
+	batchContent += unsetProviderEnvBatch(env)
 	for k, v := range env {
-		batchContent += fmt.Sprintf("set %s=%s\r\n", k, v)
+		if !isLocalOnlyEnvVar(k) {
+			batchContent += batchSetLine(k, v)
+		}
 	}
 
 	home, _ := os.UserHomeDir()
@@ -1452,10 +1460,28 @@ func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, p
 	gitBinPath := `C:\Program Files\Git\bin`
 	gitUsrBinPath := `C:\Program Files\Git\usr\bin`
 
+	if nodeBin := env[nodeBinEnvVar]; nodeBin != "" {
+		// Node.js version chosen for this tool or project
+		localToolPath = nodeBin + ";" + localToolPath
+	}
+
 	batchContent += fmt.Sprintf("set PATH=%s;%s;%s;%s;%s;%s;%%PATH%%\r\n",
 		localToolPath, npmPath, nodePath, gitCmdPath, gitBinPath, gitUsrBinPath)
 
-	if pythonEnv != "" && pythonEnv != "None (Default)" {
+	pyEnv := a.resolvePythonEnv(pythonEnv, projectDir)
+	if pyEnv != nil && pyEnv.Type != "conda" {
+		// Virtual environments and plain interpreters need no conda
+		activateScript := filepath.Join(pyEnv.Path, "Scripts", "activate.bat")
+		batchContent += fmt.Sprintf("echo Activating Python environment: %s\r\n", pyEnv.Name)
+		if _, err := os.Stat(activateScript); err == nil {
+			batchContent += fmt.Sprintf("call \"%s\"\r\n", activateScript)
+		} else {
+			batchContent += fmt.Sprintf("set PATH=%s;%s;%%PATH%%\r\n", pyEnv.Path, filepath.Join(pyEnv.Path, "Scripts"))
+		}
+	} else if pythonEnv != "" && pythonEnv != "None (Default)" {
+		if pyEnv != nil {
+			pythonEnv = pyEnv.Path
+		}
 		condaRoot := a.getCondaRoot()
 		if condaRoot != "" {
 			activateScript := filepath.Join(condaRoot, "Scripts", "activate.bat")
@@ -1481,7 +1507,7 @@ func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, p
 			var jsEntryPoint string
 			packageName := tm.GetPackageName(binaryName)
 			if packageName != "" {
-				pkgDir := filepath.Join(home, ".cceasy", "tools", "node_modules", packageName)
+				pkgDir := tm.activePackageDir(binaryName)
 
 				possibleEntries := []string{
 					filepath.Join(pkgDir, "index.js"),
@@ -1523,6 +1549,7 @@ func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, p
 	}
 
 	batchContent += "set TOOL_EXIT_CODE=%errorlevel%\r\n"
+	batchContent += exitFileBatchLine(env)
 	batchContent += "echo.\r\n"
 	batchContent += "if %TOOL_EXIT_CODE% neq 0 (\r\n"
 	batchContent += "  echo ========================================\r\n"
@@ -1580,8 +1607,11 @@ func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, p
 			codexBatchContent += "chcp 65001 > nul\r\n"
 			codexBatchContent += fmt.Sprintf("cd /d \"%s\"\r\n", projectDir)
 
+			codexBatchContent += unsetProviderEnvBatch(env)
 			for k, v := range env {
-				codexBatchContent += fmt.Sprintf("set %s=%s\r\n", k, v)
+				if !isLocalOnlyEnvVar(k) {
+					codexBatchContent += batchSetLine(k, v)
+				}
 			}
 			codexBatchContent += fmt.Sprintf("set PATH=%s;%%PATH%%\r\n", localToolPath)
 
@@ -1590,6 +1620,7 @@ func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, p
 			codexBatchContent += fmt.Sprintf("call \"%s\"%s\r\n", binaryPath, cmdArgs)
 
 			codexBatchContent += "set TOOL_EXIT_CODE=%errorlevel%\r\n"
+			codexBatchContent += exitFileBatchLine(env)
 			codexBatchContent += "echo.\r\n"
 			codexBatchContent += "if %TOOL_EXIT_CODE% neq 0 (\r\n"
 			codexBatchContent += "  echo ========================================\r\n"
@@ -1648,105 +1679,111 @@ func (a *App) platformLaunch(binaryName string, yoloMode bool, adminMode bool, p
 	}
 }
 
-func (a *App) syncToSystemEnv(config AppConfig) {
-	toolName := strings.ToLower(config.ActiveTool)
-	var toolCfg ToolConfig
-	var envKey, envBaseUrl string
-
-	switch toolName {
-	case "claude":
-		toolCfg = config.Claude
-		envKey = "ANTHROPIC_AUTH_TOKEN"
-		envBaseUrl = "ANTHROPIC_BASE_URL"
-	case "gemini":
-		toolCfg = config.Gemini
-		envKey = "GEMINI_API_KEY"
-		envBaseUrl = "GOOGLE_GEMINI_BASE_URL"
-	case "codex":
-		toolCfg = config.Codex
-		envKey = "OPENAI_API_KEY"
-		envBaseUrl = "OPENAI_BASE_URL"
-	default:
-		return
-	}
-
-	var selectedModel *ModelConfig
-	for _, m := range toolCfg.Models {
-		if m.ModelName == toolCfg.CurrentModel {
-			selectedModel = &m
-			break
+// runInTerminal opens a console window with a batch file that runs the
+// commands one after another until one fails, removes the cleanup files and
+// records the exit code.
+func (a *App) runInTerminal(cmds []terminalCommand, cleanup []string, exitFile, title string) error {
+	batchContent := "@echo off\r\n"
+	batchContent += "chcp 65001 > nul\r\n"
+	batchContent += "set TOOL_EXIT_CODE=0\r\n"
+	for _, c := range cmds {
+		words := make([]string, len(c.Args))
+		for i, arg := range c.Args {
+			words[i] = quoteBatchArg(arg)
+		}
+		line := strings.Join(words, " ")
+		if c.Stdin != "" {
+			line += fmt.Sprintf(" < \"%s\"", c.Stdin)
 		}
+		batchContent += line + "\r\n"
+		batchContent += "set TOOL_EXIT_CODE=%errorlevel%\r\n"
+		batchContent += "if not \"%TOOL_EXIT_CODE%\"==\"0\" goto done\r\n"
 	}
-
-	if selectedModel == nil {
-		return
+	batchContent += ":done\r\n"
+	for _, f := range cleanup {
+		batchContent += fmt.Sprintf("del \"%s\" 2>nul\r\n", f)
 	}
+	if exitFile != "" {
+		batchContent += fmt.Sprintf("(echo %%TOOL_EXIT_CODE%%)>\"%s\"\r\n", exitFile)
+	}
+	batchContent += "echo.\r\n"
+	batchContent += "echo Press any key to close this window...\r\n"
+	batchContent += "pause >nul\r\n"
 
-	if strings.ToLower(selectedModel.ModelName) == "original" {
-		os.Unsetenv(envKey)
-		os.Unsetenv(envBaseUrl)
-		if toolName == "codex" {
-			os.Unsetenv("WIRE_API")
-		}
+	batchPath := filepath.Join(os.TempDir(), fmt.Sprintf("aicoder_run_%d.bat", time.Now().UnixNano()))
+	if err := os.WriteFile(batchPath, []byte(batchContent), 0644); err != nil {
+		return fmt.Errorf("failed to create temporary batch file: %v", err)
+	}
 
-		go func() {
-			cmd1 := exec.Command("setx", envKey, "")
-			cmd1.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-			cmd1.Run()
+	cmd := exec.Command("cmd")
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CmdLine:    fmt.Sprintf(`cmd /c start "%s" /d "%s" cmd /c "%s"`, title, a.GetUserHomeDir(), batchPath),
+		HideWindow: true,
+	}
+	return cmd.Start()
+}
 
-			cmd2 := exec.Command("setx", envBaseUrl, "")
-			cmd2.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-			cmd2.Run()
+// persistedEnvVars are the provider variables syncToSystemEnv stores, by tool.
+var persistedEnvVars = map[string][]string{
+	"claude": {"ANTHROPIC_AUTH_TOKEN", "ANTHROPIC_BASE_URL", "ANTHROPIC_API_KEY"},
+	"gemini": {"GEMINI_API_KEY", "GOOGLE_GEMINI_BASE_URL"},
+	"codex":  {"OPENAI_API_KEY", "OPENAI_BASE_URL", "WIRE_API"},
+}
 
-			if toolName == "claude" {
-				cmd3 := exec.Command("setx", "ANTHROPIC_API_KEY", "")
-				cmd3.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-				cmd3.Run()
-			}
-			if toolName == "codex" {
-				cmd4 := exec.Command("setx", "WIRE_API", "")
-				cmd4.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-				cmd4.Run()
-			}
-		}()
-		return
+// syncToSystemEnv stores the provider variables of a launch in the user
+// environment with setx, so terminals opened later use the same provider.
+// Variables the launch does not set are cleared. Only used with the
+// PersistProviderEnv setting.
+func (a *App) syncToSystemEnv(plan *launchPlan) {
+	names := persistedEnvVars[plan.toolName]
+	values := make([]string, len(names))
+	for i, k := range names {
+		values[i] = plan.env[k]
 	}
+	go func() {
+		for i, k := range names {
+			createHiddenCmd("setx", k, values[i]).Run()
+		}
+	}()
+}
 
-	baseUrl := selectedModel.ModelUrl
-	if toolName == "claude" {
-		baseUrl = getBaseUrl(selectedModel)
+// unsetProviderEnvBatch clears provider variables inherited from AICoder's
+// environment that the launch does not set itself.
+func unsetProviderEnvBatch(env map[string]string) string {
+	content := ""
+	for _, k := range providerEnvVars {
+		if _, ok := env[k]; !ok {
+			content += fmt.Sprintf("set %s=\r\n", k)
+		}
 	}
+	return content
+}
 
-	os.Setenv(envKey, selectedModel.ApiKey)
-	if baseUrl != "" {
-		os.Setenv(envBaseUrl, baseUrl)
-	}
+// batchSetLine sets an environment variable in a .bat file. The quoted form
+// keeps characters like & and | literal, and percent signs (e.g. in escaped
+// proxy credentials) are doubled so cmd does not expand them.
+func batchSetLine(k, v string) string {
+	return fmt.Sprintf("set \"%s=%s\"\r\n", k, strings.ReplaceAll(v, "%", "%%"))
+}
 
-	if toolName == "codex" {
-		os.Setenv("WIRE_API", "responses")
+// exitFileBatchLine records the tool's exit code in the launch's exit file so
+// post-exit hooks can run. It is empty when no exit file was requested.
+func exitFileBatchLine(env map[string]string) string {
+	exitFile, ok := env[exitFileEnvVar]
+	if !ok {
+		return ""
 	}
+	return fmt.Sprintf("(echo %%TOOL_EXIT_CODE%%)>\"%s\"\r\n", strings.ReplaceAll(exitFile, "%", "%%"))
+}
 
-	go func() {
-		cmd1 := exec.Command("setx", envKey, selectedModel.ApiKey)
-		cmd1.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-		cmd1.Run()
-
-		if baseUrl != "" {
-			cmd2 := exec.Command("setx", envBaseUrl, baseUrl)
-			cmd2.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-			cmd2.Run()
-		}
-		if toolName == "claude" {
-			cmd3 := exec.Command("setx", "ANTHROPIC_API_KEY", "")
-			cmd3.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-			cmd3.Run()
-		}
-		if toolName == "codex" {
-			cmd4 := exec.Command("setx", "WIRE_API", "responses")
-			cmd4.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-			cmd4.Run()
-		}
-	}()
+// quoteBatchArg quotes an argument for a command line inside a .bat file.
+// Percent signs are doubled so cmd does not treat them as variable references.
+func quoteBatchArg(arg string) string {
+	arg = strings.ReplaceAll(arg, "%", "%%")
+	if arg != "" && !strings.ContainsAny(arg, " \t\"&|<>^()") {
+		return arg
+	}
+	return "\"" + strings.ReplaceAll(arg, "\"", "\"\"") + "\""
 }
 
 func createVersionCmd(path string) *exec.Cmd {
@@ -1867,3 +1904,16 @@ func createHiddenCmd(name string, args ...string) *exec.Cmd {
 	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
 	return cmd
 }
+
+// startProcessGroup does nothing on Windows: killProcessGroup finds the
+// children through the process tree instead.
+func startProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills a command and the processes it started.
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+	createHiddenCmd("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	cmd.Process.Kill()
+}
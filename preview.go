@@ -0,0 +1,424 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LaunchPreview describes what LaunchTool would do for a tool and project
+// without executing anything. Secrets are redacted.
+type LaunchPreview struct {
+	Tool            string              `json:"tool"`
+	Provider        string              `json:"provider"`
+	BinaryPath      string              `json:"binary_path"` // Empty when the tool is not installed yet or runs over SSH or in a container
+	Args            []string            `json:"args"`
+	CommandLine     string              `json:"command_line"`
+	WorkDir         string              `json:"work_dir"`         // On the SSH host or in the container for those launches
+	Host            string              `json:"host"`             // SSH destination of remote projects
+	LaunchMode      string              `json:"launch_mode"`      // "terminal", "tmux", "zellij", "container" or "ssh"
+	Sandbox         []string            `json:"sandbox"`          // Sandbox command the command line runs in, empty for none
+	SandboxWritable []string            `json:"sandbox_writable"` // Paths outside the project the sandboxed tool can change, e.g. ~/.claude.json
+	NodeVersion     string              `json:"node_version"`     // e.g. "20.11.1 (.nvmrc)", empty for the default Node.js
+	Env             map[string]string   `json:"env"`
+	ConfigFiles     []ConfigFilePreview `json:"config_files"`
+	PreHooks        []string            `json:"pre_hooks"`
+	PostHooks       []string            `json:"post_hooks"`
+}
+
+// ConfigFilePreview is a planned config file change compared with the file on
+// disk. Action is "create", "update", "unchanged" or "remove". Files on an SSH
+// host or in a container cannot be compared, so their Action is "write" or
+// "remove" and Path is the path there.
+type ConfigFilePreview struct {
+	Path   string `json:"path"`
+	Action string `json:"action"`
+	Diff   string `json:"diff"`
+}
+
+// PreviewLaunch returns the launch plan for a tool in a project (empty means the
+// current project) using the project's yolo, proxy and launch mode settings.
+func (a *App) PreviewLaunch(toolName string, projectDir string) (LaunchPreview, error) {
+	if projectDir == "" {
+		projectDir = a.GetCurrentProjectPath()
+	}
+	config, err := a.LoadConfig()
+	if err != nil {
+		return LaunchPreview{}, err
+	}
+	var yoloMode, useProxy bool
+	if proj := findProjectConfig(&config, projectDir); proj != nil {
+		yoloMode = proj.YoloMode
+		useProxy = proj.UseProxy
+	}
+
+	plan, err := a.buildLaunchPlan(toolName, projectDir, useProxy, "")
+	if err != nil {
+		return LaunchPreview{}, err
+	}
+
+	secrets := planSecrets(plan)
+	preview := LaunchPreview{
+		Tool:            plan.toolName,
+		Provider:        plan.model.ModelName,
+		BinaryPath:      NewToolManager(a).findToolPath(plan.binaryName),
+		Args:            append(toolLaunchArgs(plan.binaryName, yoloMode, plan.model.ModelId), plan.extraArgs...),
+		WorkDir:         plan.projectDir,
+		LaunchMode:      LaunchModeTerminal,
+		Env:             make(map[string]string),
+		Sandbox:         []string{},
+		SandboxWritable: []string{},
+		PreHooks:        []string{},
+		PostHooks:       []string{},
+	}
+	if plan.project != nil && plan.project.LaunchMode != "" {
+		preview.LaunchMode = plan.project.LaunchMode
+	}
+	if useContainer(plan, yoloMode) {
+		preview.LaunchMode = "container"
+	}
+	if plan.project != nil && plan.project.SSHHost != "" {
+		return a.previewRemoteLaunch(plan, preview, secrets), nil
+	}
+	if useContainer(plan, yoloMode) {
+		return a.previewContainerLaunch(plan, preview, secrets), nil
+	}
+
+	switch {
+	case plan.node != nil:
+		preview.NodeVersion = fmt.Sprintf("%s (%s)", plan.node.Version, plan.nodeSource)
+	case plan.nodeSpec != "":
+		preview.NodeVersion = fmt.Sprintf("%s (%s, installed on launch)", plan.nodeSpec, plan.nodeSource)
+	}
+
+	binary := preview.BinaryPath
+	if binary == "" {
+		binary = plan.binaryName
+	}
+	preview.Sandbox = append(preview.Sandbox, plan.sandbox...)
+	if len(plan.sandbox) > 0 {
+		preview.SandboxWritable = append(preview.SandboxWritable, a.sandboxWritablePaths(plan.project.Sandbox, plan.toolName)...)
+	}
+	cmdLine := ""
+	for _, arg := range plan.sandbox {
+		cmdLine += shellQuote(arg) + " "
+	}
+	cmdLine += shellQuote(binary)
+	for i, arg := range preview.Args {
+		preview.Args[i] = redactSecrets(arg, secrets)
+		cmdLine += " " + shellQuote(preview.Args[i])
+	}
+	preview.CommandLine = cmdLine
+
+	for k, v := range plan.env {
+		preview.Env[k] = redactSecrets(v, secrets)
+	}
+	for _, c := range plan.configFiles {
+		preview.ConfigFiles = append(preview.ConfigFiles, previewConfigFile(c, secrets))
+	}
+	for _, h := range plan.preHooks {
+		preview.PreHooks = append(preview.PreHooks, h.Command)
+	}
+	for _, h := range plan.postHooks {
+		preview.PostHooks = append(preview.PostHooks, h.Command)
+	}
+	return preview, nil
+}
+
+// previewRemoteLaunch completes the preview of a launch on the project's SSH
+// host, which runs the tool found in the remote PATH in the remote project
+// directory, with the config files written there.
+func (a *App) previewRemoteLaunch(plan *launchPlan, preview LaunchPreview, secrets []string) LaunchPreview {
+	preview.LaunchMode = "ssh"
+	preview.Host = sshDestination(plan.project)
+	preview.BinaryPath = ""
+	preview.WorkDir = plan.project.RemotePath
+	if preview.WorkDir == "" {
+		preview.WorkDir = "~"
+	}
+	cmdLine := "ssh -t " + shellQuote(preview.Host) + " " + shellQuote(plan.binaryName)
+	for i, arg := range preview.Args {
+		preview.Args[i] = redactSecrets(arg, secrets)
+		cmdLine += " " + shellQuote(preview.Args[i])
+	}
+	preview.CommandLine = cmdLine
+
+	for k, v := range plan.env {
+		if !isLocalOnlyEnvVar(k) {
+			preview.Env[k] = redactSecrets(v, secrets)
+		}
+	}
+	home := a.GetUserHomeDir()
+	for _, c := range plan.configFiles {
+		path, ok := remoteConfigPath(c.Path, home, plan.projectDir, preview.WorkDir)
+		if !ok {
+			continue
+		}
+		p := ConfigFilePreview{Path: path, Action: "write"}
+		switch {
+		case c.Remove && c.IsDir:
+			p.Action = "remove"
+			p.Diff = fmt.Sprintf("directory %s and its contents would be removed", path)
+		case c.Remove:
+			p.Action = "remove"
+		default:
+			p.Diff = lineDiff("", redactSecrets(c.Content, secrets))
+		}
+		preview.ConfigFiles = append(preview.ConfigFiles, p)
+	}
+	for _, h := range plan.preHooks {
+		preview.PreHooks = append(preview.PreHooks, h.Command)
+	}
+	for _, h := range plan.postHooks {
+		preview.PostHooks = append(preview.PostHooks, h.Command)
+	}
+	return preview
+}
+
+// previewContainerLaunch completes the preview of a launch in a container: the
+// docker or podman command, and the config files written into the container's
+// home and the mounted project before the tool installed there starts.
+func (a *App) previewContainerLaunch(plan *launchPlan, preview LaunchPreview, secrets []string) LaunchPreview {
+	preview.BinaryPath = ""
+	preview.WorkDir = containerWorkspace
+	runtimeName, runtimePath, err := findContainerRuntime(plan.project.ContainerRuntime)
+	if err != nil {
+		runtimeName = plan.project.ContainerRuntime
+		if runtimeName == "" {
+			runtimeName = "docker"
+		}
+		runtimePath = runtimeName
+	}
+	if env, changed := containerProxyEnv(plan.env, runtimeName, plan.project.ContainerNetwork); changed {
+		contained := *plan
+		contained.env = env
+		plan = &contained
+	}
+
+	scriptPath := filepath.Join(a.GetUserHomeDir(), ".cceasy", "container", "launch.sh")
+	cmdLine := shellQuote(runtimePath)
+	for _, arg := range a.containerRunArgs(runtimeName, plan, scriptPath) {
+		cmdLine += " " + shellQuote(arg)
+	}
+	preview.CommandLine = cmdLine
+	for i, arg := range preview.Args {
+		preview.Args[i] = redactSecrets(arg, secrets)
+	}
+
+	for k, v := range plan.env {
+		if !isLocalOnlyEnvVar(k) {
+			preview.Env[k] = redactSecrets(v, secrets)
+		}
+	}
+	home := a.GetUserHomeDir()
+	for _, c := range plan.configFiles {
+		path, ok := remoteConfigPath(c.Path, home, plan.projectDir, containerWorkspace)
+		if !ok {
+			continue
+		}
+		content := c.Content
+		if strings.HasPrefix(path, "~/") {
+			path = containerHome + path[1:]
+			if c.Merge != "" {
+				content = c.Merge
+			}
+		}
+		p := ConfigFilePreview{Path: path, Action: "write"}
+		switch {
+		case c.Remove && c.IsDir:
+			p.Action = "remove"
+			p.Diff = fmt.Sprintf("directory %s and its contents would be removed", path)
+		case c.Remove:
+			p.Action = "remove"
+		default:
+			p.Diff = lineDiff("", redactSecrets(content, secrets))
+		}
+		preview.ConfigFiles = append(preview.ConfigFiles, p)
+	}
+	for _, h := range plan.preHooks {
+		preview.PreHooks = append(preview.PreHooks, h.Command)
+	}
+	for _, h := range plan.postHooks {
+		preview.PostHooks = append(preview.PostHooks, h.Command)
+	}
+	return preview
+}
+
+// planSecrets returns the values in a plan that must not be shown: the API key,
+// the provider credential variables and proxy passwords. Other variables are
+// shown as is, even if their names mention keys or tokens.
+func planSecrets(plan *launchPlan) []string {
+	var secrets []string
+	if plan.model.ApiKey != "" {
+		secrets = append(secrets, plan.model.ApiKey)
+	}
+	for _, k := range providerEnvVars {
+		if v := plan.env[k]; v != "" && isCredentialEnvVar(k) {
+			secrets = append(secrets, v)
+		}
+	}
+	for k, v := range plan.env {
+		if strings.HasSuffix(strings.ToUpper(k), "_PROXY") {
+			if u, err := url.Parse(v); err == nil && u.User != nil {
+				if p, ok := u.User.Password(); ok && p != "" {
+					// The URL holds the escaped form of the password
+					secrets = append(secrets, p, strings.SplitN(u.User.String(), ":", 2)[1])
+				}
+			}
+		}
+	}
+	return secrets
+}
+
+// isCredentialEnvVar reports whether a provider variable holds an API key or
+// access token.
+func isCredentialEnvVar(name string) bool {
+	return strings.HasSuffix(name, "_API_KEY") || strings.HasSuffix(name, "_AUTH_TOKEN") || strings.HasSuffix(name, "_ACCESS_TOKEN")
+}
+
+// redactSecrets replaces every secret in s, keeping the first four characters of
+// longer secrets so keys can still be told apart.
+func redactSecrets(s string, secrets []string) string {
+	for _, secret := range secrets {
+		masked := "****"
+		if len(secret) > 12 {
+			masked = secret[:4] + "****"
+		}
+		s = strings.ReplaceAll(s, secret, masked)
+	}
+	return s
+}
+
+func previewConfigFile(c ConfigFileChange, secrets []string) ConfigFilePreview {
+	p := ConfigFilePreview{Path: c.Path}
+	info, statErr := os.Stat(c.Path)
+	if c.Remove {
+		switch {
+		case statErr != nil:
+			p.Action = "unchanged"
+		case info.IsDir():
+			p.Action = "remove"
+			p.Diff = fmt.Sprintf("directory %s and its contents would be removed", c.Path)
+		default:
+			p.Action = "remove"
+			old, _ := os.ReadFile(c.Path)
+			p.Diff = lineDiff(redactSecrets(string(old), secrets), "")
+		}
+		return p
+	}
+
+	old, err := os.ReadFile(c.Path)
+	switch {
+	case err != nil:
+		p.Action = "create"
+	case string(old) == c.Content:
+		p.Action = "unchanged"
+		return p
+	default:
+		p.Action = "update"
+	}
+	p.Diff = lineDiff(redactSecrets(string(old), secrets), redactSecrets(c.Content, secrets))
+	return p
+}
+
+// maxDiffCells bounds the size of the table lineDiff compares the changed
+// lines with. Larger changes are summarized instead of diffed.
+const maxDiffCells = 1 << 18
+
+// lineDiff returns a unified-style line diff of two texts. Runs of unchanged
+// lines longer than the context are collapsed.
+func lineDiff(oldText, newText string) string {
+	const context = 3
+	a := splitLines(oldText)
+	b := splitLines(newText)
+
+	// Only the lines between the common prefix and suffix are compared
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	var lines []string
+	for _, l := range a[:prefix] {
+		lines = append(lines, " "+l)
+	}
+	tail := a[len(a)-suffix:]
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		return fmt.Sprintf("@@ file replaced (%d lines, previously %d) @@\n", len(b)+prefix+suffix, len(a)+prefix+suffix)
+	}
+
+	// Longest common subsequence table
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, " "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "-"+a[i])
+			i++
+		default:
+			lines = append(lines, "+"+b[j])
+			j++
+		}
+	}
+	for _, l := range tail {
+		lines = append(lines, " "+l)
+	}
+
+	// Keep only changed lines and their context
+	keep := make([]bool, len(lines))
+	for k, l := range lines {
+		if l[0] != ' ' {
+			for c := k - context; c <= k+context; c++ {
+				if c >= 0 && c < len(lines) {
+					keep[c] = true
+				}
+			}
+		}
+	}
+	var sb strings.Builder
+	skipped := false
+	for k, l := range lines {
+		if !keep[k] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			sb.WriteString("@@ ... @@\n")
+			skipped = false
+		}
+		sb.WriteString(l + "\n")
+	}
+	return sb.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PromptRun describes a non-interactive (one-shot) tool run.
+type PromptRun struct {
+	Id         string `json:"id"`
+	Tool       string `json:"tool"`
+	Provider   string `json:"provider"`
+	ProjectDir string `json:"project_dir"`
+	Prompt     string `json:"prompt"`
+	LogPath    string `json:"log_path"`
+	Status     string `json:"status"` // "running", "succeeded", "failed" or "cancelled"
+	ExitCode   int    `json:"exit_code"`
+	Error      string `json:"error"`
+	StartedAt  string `json:"started_at"`  // RFC3339
+	FinishedAt string `json:"finished_at"` // RFC3339, empty while running
+}
+
+// PromptRunOutput is emitted as a "prompt-run-output" event for each output line.
+type PromptRunOutput struct {
+	Id   string `json:"id"`
+	Line string `json:"line"`
+}
+
+// maxFinishedPromptRuns is how many finished runs ListPromptRuns keeps. Their
+// logs stay in the runs directory.
+const maxFinishedPromptRuns = 50
+
+type promptRunState struct {
+	run       PromptRun
+	cmd       *exec.Cmd
+	cancelled bool
+}
+
+// headlessArgs returns the arguments that run a tool non-interactively with the
+// given prompt. Subcommands (codex exec, opencode run) come before args.
+func headlessArgs(binaryName string, args []string, prompt string) ([]string, error) {
+	switch binaryName {
+	case "claude", "gemini", "codebuddy", "qoder", "iflow", "kode":
+		return append(append([]string{}, args...), "-p", prompt), nil
+	case "codex":
+		return append(append([]string{"exec"}, args...), prompt), nil
+	case "opencode":
+		return append(append([]string{"run"}, args...), prompt), nil
+	case "kilo":
+		return append(append([]string{}, args...), "--auto", prompt), nil
+	case "aider":
+		return append(append([]string{}, args...), "--message", prompt), nil
+	}
+	return nil, fmt.Errorf("%s does not support headless prompts", binaryName)
+}
+
+func (a *App) getRunsDir() string {
+	return filepath.Join(a.GetUserHomeDir(), ".cceasy", "runs")
+}
+
+// RunPrompt runs a tool non-interactively in projectDir with the given prompt,
+// e.g. `claude -p` or `codex exec`. The environment is the one LaunchTool would
+// prepare for the provider (empty means the tool's current provider). Output is
+// written to a per-run log file and streamed as "prompt-run-output" events; a
+// "prompt-run-done" event carries the final PromptRun.
+func (a *App) RunPrompt(toolName string, provider string, projectDir string, prompt string, yoloMode bool, useProxy bool) (PromptRun, error) {
+	if strings.TrimSpace(prompt) == "" {
+		return PromptRun{}, fmt.Errorf("prompt is empty")
+	}
+	if projectDir == "" {
+		projectDir = a.GetCurrentProjectPath()
+	}
+
+	prep, err := a.prepareLaunch(toolName, projectDir, useProxy, provider)
+	if err != nil {
+		return PromptRun{}, err
+	}
+
+	tm := NewToolManager(a)
+	status := tm.GetToolStatus(prep.binaryName)
+	if !status.Installed {
+		return PromptRun{}, fmt.Errorf("%s is not installed", prep.binaryName)
+	}
+
+	args, err := headlessArgs(prep.binaryName, append(toolLaunchArgs(prep.binaryName, yoloMode, prep.model.ModelId), prep.extraArgs...), prompt)
+	if err != nil {
+		return PromptRun{}, err
+	}
+
+	now := time.Now()
+	run := PromptRun{
+		Id:         fmt.Sprintf("%s-%s-%d", now.Format("20060102-150405"), prep.binaryName, now.UnixNano()%100000),
+		Tool:       prep.toolName,
+		Provider:   prep.model.ModelName,
+		ProjectDir: projectDir,
+		Prompt:     prompt,
+		Status:     "running",
+		StartedAt:  now.Format(time.RFC3339),
+	}
+
+	runsDir := a.getRunsDir()
+	if err := os.MkdirAll(runsDir, 0755); err != nil {
+		return PromptRun{}, fmt.Errorf("failed to create runs directory: %v", err)
+	}
+	run.LogPath = filepath.Join(runsDir, run.Id+".log")
+	logFile, err := os.Create(run.LogPath)
+	if err != nil {
+		return PromptRun{}, fmt.Errorf("failed to create run log: %v", err)
+	}
+	fmt.Fprintf(logFile, "# %s (%s) in %s\n# prompt: %s\n\n", run.Tool, run.Provider, projectDir, prompt)
+
+	cmd := createHiddenCmd(status.Path, args...)
+	if len(prep.sandbox) > 0 {
+		cmd = createHiddenCmd(prep.sandbox[0], append(append(prep.sandbox[1:], status.Path), args...)...)
+	}
+	cmd.Dir = projectDir
+	cmd.Env = buildChildEnv(prep.env)
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	// Background children may keep the output open after the tool exits
+	cmd.WaitDelay = time.Second
+	startProcessGroup(cmd)
+
+	a.log(fmt.Sprintf("RunPrompt %s: %s %s", run.Id, status.Path, strings.Join(args[:len(args)-1], " ")))
+	if err := cmd.Start(); err != nil {
+		logFile.Close()
+		return PromptRun{}, fmt.Errorf("failed to start %s: %v", prep.binaryName, err)
+	}
+
+	state := &promptRunState{run: run, cmd: cmd}
+	a.promptRunMutex.Lock()
+	a.promptRuns[run.Id] = state
+	a.promptRunMutex.Unlock()
+
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		scanner := bufio.NewScanner(pr)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			fmt.Fprintln(logFile, line)
+			a.emitEvent("prompt-run-output", PromptRunOutput{Id: run.Id, Line: line})
+		}
+		io.Copy(io.Discard, pr)
+	}()
+
+	go func() {
+		waitErr := cmd.Wait()
+		if errors.Is(waitErr, exec.ErrWaitDelay) {
+			waitErr = nil
+		}
+		pw.Close()
+		<-outputDone
+
+		a.promptRunMutex.Lock()
+		r := &state.run
+		r.FinishedAt = time.Now().Format(time.RFC3339)
+		r.ExitCode = cmd.ProcessState.ExitCode()
+		switch {
+		case state.cancelled:
+			r.Status = "cancelled"
+		case waitErr != nil:
+			r.Status = "failed"
+			r.Error = waitErr.Error()
+		default:
+			r.Status = "succeeded"
+		}
+		finished := *r
+		a.prunePromptRuns()
+		a.promptRunMutex.Unlock()
+
+		fmt.Fprintf(logFile, "\n# %s, exit code %d\n", finished.Status, finished.ExitCode)
+		logFile.Close()
+		a.log(fmt.Sprintf("RunPrompt %s %s (exit code %d)", finished.Id, finished.Status, finished.ExitCode))
+		a.emitEvent("prompt-run-done", finished)
+	}()
+
+	return run, nil
+}
+
+// RunPromptInProjects starts the same prompt in several project directories.
+// Runs that fail to start are returned with status "failed".
+func (a *App) RunPromptInProjects(toolName string, provider string, projectDirs []string, prompt string, yoloMode bool, useProxy bool) []PromptRun {
+	runs := make([]PromptRun, 0, len(projectDirs))
+	for _, dir := range projectDirs {
+		run, err := a.RunPrompt(toolName, provider, dir, prompt, yoloMode, useProxy)
+		if err != nil {
+			run = PromptRun{Tool: strings.ToLower(toolName), Provider: provider, ProjectDir: dir, Prompt: prompt, Status: "failed", ExitCode: -1, Error: err.Error()}
+		}
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// prunePromptRuns forgets the oldest finished runs beyond maxFinishedPromptRuns.
+// The caller holds promptRunMutex.
+func (a *App) prunePromptRuns() {
+	var finished []*promptRunState
+	for _, s := range a.promptRuns {
+		if s.run.Status != "running" {
+			finished = append(finished, s)
+		}
+	}
+	if len(finished) <= maxFinishedPromptRuns {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].run.FinishedAt < finished[j].run.FinishedAt })
+	for _, s := range finished[:len(finished)-maxFinishedPromptRuns] {
+		delete(a.promptRuns, s.run.Id)
+	}
+}
+
+// ListPromptRuns returns the runs started since AICoder was opened: the running
+// ones and the last maxFinishedPromptRuns finished ones.
+func (a *App) ListPromptRuns() []PromptRun {
+	a.promptRunMutex.Lock()
+	defer a.promptRunMutex.Unlock()
+	runs := make([]PromptRun, 0, len(a.promptRuns))
+	for _, s := range a.promptRuns {
+		runs = append(runs, s.run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt < runs[j].StartedAt })
+	return runs
+}
+
+// CancelPromptRun stops a running prompt and the processes it started.
+func (a *App) CancelPromptRun(id string) error {
+	a.promptRunMutex.Lock()
+	defer a.promptRunMutex.Unlock()
+	s, ok := a.promptRuns[id]
+	if !ok {
+		return fmt.Errorf("unknown run: %s", id)
+	}
+	if s.run.Status != "running" {
+		return nil
+	}
+	s.cancelled = true
+	killProcessGroup(s.cmd)
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPrunePromptRuns(t *testing.T) {
+	a := &App{promptRuns: make(map[string]*promptRunState)}
+	for i := 0; i < maxFinishedPromptRuns+5; i++ {
+		id := fmt.Sprintf("finished-%03d", i)
+		a.promptRuns[id] = &promptRunState{run: PromptRun{Id: id, Status: "succeeded", FinishedAt: fmt.Sprintf("2024-01-01T00:%02d:%02dZ", i/60, i%60)}}
+	}
+	a.promptRuns["running"] = &promptRunState{run: PromptRun{Id: "running", Status: "running"}}
+
+	a.prunePromptRuns()
+	if len(a.promptRuns) != maxFinishedPromptRuns+1 {
+		t.Fatalf("%d runs kept, want %d", len(a.promptRuns), maxFinishedPromptRuns+1)
+	}
+	for _, id := range []string{"running", "finished-005", fmt.Sprintf("finished-%03d", maxFinishedPromptRuns+4)} {
+		if _, ok := a.promptRuns[id]; !ok {
+			t.Errorf("run %s was pruned", id)
+		}
+	}
+	if _, ok := a.promptRuns["finished-004"]; ok {
+		t.Errorf("oldest runs were kept")
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultNoProxy keeps local traffic off the proxy when no list is configured.
+const defaultNoProxy = "localhost,127.0.0.1,::1"
+
+// ProxySettings is a proxy for tool launches, either a project's or the global
+// default.
+type ProxySettings struct {
+	Scheme   string `json:"scheme"` // "http" (default), "https" or "socks5"
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	NoProxy  string `json:"no_proxy"` // Comma separated hosts and domains that bypass the proxy
+}
+
+// ProxyTestResult is the outcome of TestProxy.
+type ProxyTestResult struct {
+	Success    bool   `json:"success"`
+	ProxyURL   string `json:"proxy_url"` // Password redacted
+	TargetURL  string `json:"target_url"`
+	StatusCode int    `json:"status_code"`
+	LatencyMs  int64  `json:"latency_ms"`
+	Error      string `json:"error"`
+}
+
+// resolveProxySettings returns the proxy of the project, falling back to the
+// global default when the project has no host configured.
+func resolveProxySettings(config *AppConfig, proj *ProjectConfig) ProxySettings {
+	if proj != nil && proj.ProxyHost != "" {
+		return ProxySettings{
+			Scheme:   proj.ProxyScheme,
+			Host:     proj.ProxyHost,
+			Port:     proj.ProxyPort,
+			Username: proj.ProxyUsername,
+			Password: proj.ProxyPassword,
+			NoProxy:  proj.ProxyNoProxy,
+		}
+	}
+	return ProxySettings{
+		Scheme:   config.DefaultProxyScheme,
+		Host:     config.DefaultProxyHost,
+		Port:     config.DefaultProxyPort,
+		Username: config.DefaultProxyUsername,
+		Password: config.DefaultProxyPassword,
+		NoProxy:  config.DefaultProxyNoProxy,
+	}
+}
+
+// URL returns the proxy URL with escaped credentials.
+func (p ProxySettings) URL() (*url.URL, error) {
+	if p.Host == "" || p.Port == "" {
+		return nil, fmt.Errorf("proxy host and port are required")
+	}
+	scheme := strings.ToLower(p.Scheme)
+	switch scheme {
+	case "":
+		scheme = "http"
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %s", p.Scheme)
+	}
+	u := &url.URL{Scheme: scheme, Host: net.JoinHostPort(p.Host, p.Port)}
+	if p.Username != "" {
+		if p.Password != "" {
+			u.User = url.UserPassword(p.Username, p.Password)
+		} else {
+			u.User = url.User(p.Username)
+		}
+	}
+	return u, nil
+}
+
+// noProxyList returns the NO_PROXY value, normalized to a comma separated list.
+func (p ProxySettings) noProxyList() string {
+	hosts := strings.FieldsFunc(p.NoProxy, func(r rune) bool { return r == ',' || r == ';' || r == ' ' || r == '\n' })
+	if len(hosts) == 0 {
+		return defaultNoProxy
+	}
+	return strings.Join(hosts, ",")
+}
+
+// proxyEnv sets the proxy variables for a launch in env. Both cases are set
+// because tools differ in which they read; ALL_PROXY covers SOCKS-only clients.
+func (p ProxySettings) proxyEnv(env map[string]string) error {
+	u, err := p.URL()
+	if err != nil {
+		return err
+	}
+	proxyURL := u.String()
+	noProxy := p.noProxyList()
+	for _, k := range []string{"HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY"} {
+		env[k] = proxyURL
+		env[strings.ToLower(k)] = proxyURL
+	}
+	env["NO_PROXY"] = noProxy
+	env["no_proxy"] = noProxy
+	return nil
+}
+
+// TestProxy sends a request to targetURL (a provider's base URL; empty means
+// the Anthropic API) through the proxy and reports the latency. Any HTTP
+// response counts as success, since only the connection is being tested.
+func (a *App) TestProxy(settings ProxySettings, targetURL string) ProxyTestResult {
+	if targetURL == "" {
+		targetURL = "https://api.anthropic.com"
+	}
+	result := ProxyTestResult{TargetURL: targetURL}
+
+	u, err := settings.URL()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.ProxyURL = u.String()
+	if _, ok := u.User.Password(); ok {
+		result.ProxyURL = fmt.Sprintf("%s://%s:****@%s", u.Scheme, url.User(u.User.Username()).String(), u.Host)
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{Proxy: http.ProxyURL(u)},
+		// Redirects would measure more than one round trip
+		CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, targetURL, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		a.log(fmt.Sprintf("Proxy test via %s failed: %v", result.ProxyURL, err))
+		return result
+	}
+	resp.Body.Close()
+	result.Success = true
+	result.StatusCode = resp.StatusCode
+	a.log(fmt.Sprintf("Proxy test via %s: HTTP %d in %d ms", result.ProxyURL, resp.StatusCode, result.LatencyMs))
+	return result
+}
+
+// Provider proxy policies. An empty policy inherits the project's proxy switch.
+const (
+	ProxyPolicyAlways = "always"
+	ProxyPolicyNever  = "never"
+)
+
+// providerUsesProxy applies a provider's proxy policy to the project's proxy
+// switch.
+func providerUsesProxy(model *ModelConfig, useProxy bool) bool {
+	switch strings.ToLower(model.ProxyPolicy) {
+	case ProxyPolicyAlways:
+		return true
+	case ProxyPolicyNever:
+		return false
+	}
+	return useProxy
+}
+
+// providerProxied reports whether a launch of the tool's current provider in
+// projectDir goes through a proxy.
+func providerProxied(config *AppConfig, toolName, projectDir string) bool {
+	toolCfg := getToolConfig(config, toolName)
+	if toolCfg == nil {
+		return false
+	}
+	model := getProviderModel(toolCfg, toolCfg.CurrentModel)
+	if model == nil {
+		return false
+	}
+	proj := findProjectConfig(config, projectDir)
+	if !providerUsesProxy(model, proj != nil && proj.UseProxy) {
+		return false
+	}
+	proxy := resolveProxySettings(config, proj)
+	return proxy.Host != "" && proxy.Port != ""
+}
+
+// trayProxiedProvider returns the tray item key ("tool-provider") of the active
+// provider when a launch from the tray goes through a proxy, or "" otherwise.
+func (a *App) trayProxiedProvider(config *AppConfig) string {
+	toolCfg := getToolConfig(config, config.ActiveTool)
+	if toolCfg == nil {
+		return ""
+	}
+	// The tray launches in the current project, like GetCurrentProjectPath
+	projectDir := ""
+	for _, p := range config.Projects {
+		if p.Id == config.CurrentProject {
+			projectDir = p.Path
+		}
+	}
+	if projectDir == "" && len(config.Projects) > 0 {
+		projectDir = config.Projects[0].Path
+	}
+	if !providerProxied(config, config.ActiveTool, projectDir) {
+		return ""
+	}
+	return config.ActiveTool + "-" + toolCfg.CurrentModel
+}
+
+// trayProviderTitle returns the title of a tray provider item, marking it when
+// it is the proxied one.
+func (a *App) trayProviderTitle(key, proxiedKey string) string {
+	name := key[strings.Index(key, "-")+1:]
+	if key != proxiedKey {
+		return name
+	}
+	t, ok := trayTranslations[a.CurrentLanguage]
+	if !ok {
+		t = trayTranslations["en"]
+	}
+	return fmt.Sprintf(t["proxied"], name)
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	goruntime "runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// resolvePythonEnv finds the environment selected for a launch. pythonEnv is
+// either an absolute path to an environment or the name of one listed by
+// ListPythonEnvironments for the project. Environments listed before are
+// looked up by their path; the full detection only runs for unknown names.
+func (a *App) resolvePythonEnv(pythonEnv string, projectDir string) *PythonEnvironment {
+	if pythonEnv == "" || pythonEnv == "None (Default)" {
+		return nil
+	}
+	// Only absolute paths are taken as paths: a name like ".venv" would be
+	// resolved against AICoder's working directory instead of the project
+	if filepath.IsAbs(pythonEnv) {
+		if info, err := os.Stat(pythonEnv); err == nil && info.IsDir() {
+			return describePythonDir(pythonEnv, filepath.Base(pythonEnv), "")
+		}
+	}
+	a.pythonEnvMutex.Lock()
+	env, ok := a.pythonEnvCache[projectDir+"\x00"+pythonEnv]
+	a.pythonEnvMutex.Unlock()
+	if ok {
+		if _, err := os.Stat(env.Python); err == nil {
+			return &env
+		}
+	}
+	for _, env := range a.listPythonEnvironments(projectDir) {
+		if env.Name == pythonEnv {
+			e := env
+			return &e
+		}
+	}
+	return nil
+}
+
+// venvPython returns the interpreter inside an environment directory, or "".
+func venvPython(dir string) string {
+	candidates := []string{filepath.Join(dir, "bin", "python"), filepath.Join(dir, "bin", "python3")}
+	if goruntime.GOOS == "windows" {
+		candidates = []string{filepath.Join(dir, "Scripts", "python.exe"), filepath.Join(dir, "python.exe")}
+	}
+	for _, c := range candidates {
+		if _, err := os.Stat(c); err == nil {
+			return c
+		}
+	}
+	return ""
+}
+
+// describePythonDir classifies an environment directory: conda (conda-meta),
+// a virtual environment (pyvenv.cfg, created by uv when it says so) or a plain
+// interpreter prefix. envType overrides the virtual environment type, e.g.
+// "poetry". It returns nil when dir has no interpreter.
+func describePythonDir(dir string, name string, envType string) *PythonEnvironment {
+	python := venvPython(dir)
+	if python == "" {
+		return nil
+	}
+	env := &PythonEnvironment{Name: name, Path: dir, Python: python, Type: "system"}
+	if _, err := os.Stat(filepath.Join(dir, "conda-meta")); err == nil {
+		env.Type = "conda"
+		if matches, _ := filepath.Glob(filepath.Join(dir, "conda-meta", "python-[0-9]*.json")); len(matches) > 0 {
+			v := strings.TrimPrefix(filepath.Base(matches[0]), "python-")
+			env.Version = strings.SplitN(v, "-", 2)[0]
+		}
+		return env
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "pyvenv.cfg")); err == nil {
+		env.Type = "venv"
+		for _, line := range strings.Split(string(data), "\n") {
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+			switch key {
+			case "uv":
+				env.Type = "uv"
+			case "version", "version_info":
+				if env.Version == "" {
+					env.Version = value
+				}
+			}
+		}
+		if envType != "" {
+			env.Type = envType
+		}
+	}
+	return env
+}
+
+// ListPythonEnvironments returns the Python environments available to the
+// current project: project-local virtual environments first, then conda,
+// poetry, uv, pyenv and system interpreters.
+func (a *App) ListPythonEnvironments() []PythonEnvironment {
+	envs := []PythonEnvironment{}
+	// Add default "None" option
+	envs = append(envs, PythonEnvironment{
+		Name: "None (Default)",
+		Path: "",
+		Type: "system",
+	})
+	return append(envs, a.listPythonEnvironments(a.GetCurrentProjectPath())...)
+}
+
+func (a *App) listPythonEnvironments(projectDir string) []PythonEnvironment {
+	var envs []PythonEnvironment
+	seen := make(map[string]bool)
+	add := func(env *PythonEnvironment) {
+		if env == nil {
+			return
+		}
+		key := env.Path
+		if resolved, err := filepath.EvalSymlinks(env.Path); err == nil {
+			key = resolved
+		}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		envs = append(envs, *env)
+	}
+
+	for _, env := range a.projectPythonEnvs(projectDir) {
+		add(&env)
+	}
+	condaEnvs := a.detectCondaEnvironments()
+	sort.Slice(condaEnvs, func(i, j int) bool { return condaEnvs[i].Name < condaEnvs[j].Name })
+	for _, env := range condaEnvs {
+		e := describePythonDir(env.Path, env.Name, "")
+		if e == nil {
+			e = &PythonEnvironment{Name: env.Name, Path: env.Path, Type: "conda"}
+		}
+		e.Type = "conda"
+		add(e)
+	}
+	for _, env := range poetryPythonEnvs(projectDir) {
+		add(&env)
+	}
+	home := a.GetUserHomeDir()
+	for _, env := range listVersionDirs(uvPythonDir(home), "uv") {
+		add(&env)
+	}
+	for _, env := range listVersionDirs(pyenvVersionsDir(home), "pyenv") {
+		add(&env)
+	}
+	for _, env := range a.systemPythons() {
+		add(&env)
+	}
+
+	a.pythonEnvMutex.Lock()
+	if a.pythonEnvCache == nil {
+		a.pythonEnvCache = make(map[string]PythonEnvironment)
+	}
+	for _, env := range envs {
+		if env.Python != "" {
+			a.pythonEnvCache[projectDir+"\x00"+env.Name] = env
+		}
+	}
+	a.pythonEnvMutex.Unlock()
+	return envs
+}
+
+// poetryPythonEnvs lists the environments poetry keeps outside of projects.
+// Poetry names them <project>-<hash>-py<version>, so those of projectDir are
+// marked project-local.
+func poetryPythonEnvs(projectDir string) []PythonEnvironment {
+	var envs []PythonEnvironment
+	projectName := strings.ToLower(filepath.Base(projectDir))
+	for _, dir := range poetryVirtualenvDirs() {
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			if e := describePythonDir(filepath.Join(dir, entry.Name()), entry.Name(), "poetry"); e != nil {
+				e.ProjectLocal = projectDir != "" && strings.HasPrefix(strings.ToLower(entry.Name()), projectName+"-")
+				envs = append(envs, *e)
+			}
+		}
+	}
+	return envs
+}
+
+// projectPythonEnvs finds virtual environments inside the project directory.
+// In-project poetry environments are reported as poetry.
+func (a *App) projectPythonEnvs(projectDir string) []PythonEnvironment {
+	var envs []PythonEnvironment
+	if projectDir == "" {
+		return envs
+	}
+	envType := ""
+	if data, err := os.ReadFile(filepath.Join(projectDir, "pyproject.toml")); err == nil && strings.Contains(string(data), "[tool.poetry]") {
+		envType = "poetry"
+	}
+	for _, name := range []string{".venv", "venv", "env", ".env"} {
+		dir := filepath.Join(projectDir, name)
+		if _, err := os.Stat(filepath.Join(dir, "pyvenv.cfg")); err != nil {
+			continue
+		}
+		if env := describePythonDir(dir, name, envType); env != nil {
+			env.ProjectLocal = true
+			envs = append(envs, *env)
+		}
+	}
+	return envs
+}
+
+// DetectProjectPythonEnv returns the name of the project-local environment that
+// should be pre-selected for projectDir, or "" if there is none. Only
+// environments in the project and poetry's can be project-local, so nothing
+// else is detected.
+func (a *App) DetectProjectPythonEnv(projectDir string) string {
+	if envs := a.projectPythonEnvs(projectDir); len(envs) > 0 {
+		return envs[0].Name
+	}
+	for _, env := range poetryPythonEnvs(projectDir) {
+		if env.ProjectLocal {
+			return env.Name
+		}
+	}
+	return ""
+}
+
+// preselectProjectPythonEnvs selects the project-local Python environment for
+// projects that were just added or opened and have none selected yet.
+func (a *App) preselectProjectPythonEnvs(oldConfig *AppConfig, config *AppConfig) {
+	known := make(map[string]bool)
+	for _, p := range oldConfig.Projects {
+		known[p.Id] = true
+	}
+	for i := range config.Projects {
+		p := &config.Projects[i]
+		opened := !known[p.Id] || (p.Id == config.CurrentProject && oldConfig.CurrentProject != config.CurrentProject)
+		if !opened || p.Path == "" || (p.PythonEnv != "" && p.PythonEnv != "None (Default)") {
+			continue
+		}
+		if name := a.DetectProjectPythonEnv(p.Path); name != "" {
+			a.log(fmt.Sprintf("Project %s: selected Python environment %s", p.Name, name))
+			p.PythonProject = true
+			p.PythonEnv = name
+		}
+	}
+}
+
+func poetryVirtualenvDirs() []string {
+	home, _ := os.UserHomeDir()
+	dirs := []string{}
+	if cache := os.Getenv("POETRY_CACHE_DIR"); cache != "" {
+		dirs = append(dirs, filepath.Join(cache, "virtualenvs"))
+	}
+	switch goruntime.GOOS {
+	case "windows":
+		dirs = append(dirs, filepath.Join(os.Getenv("LOCALAPPDATA"), "pypoetry", "Cache", "virtualenvs"))
+	case "darwin":
+		dirs = append(dirs, filepath.Join(home, "Library", "Caches", "pypoetry", "virtualenvs"))
+	default:
+		cache := os.Getenv("XDG_CACHE_HOME")
+		if cache == "" {
+			cache = filepath.Join(home, ".cache")
+		}
+		dirs = append(dirs, filepath.Join(cache, "pypoetry", "virtualenvs"))
+	}
+	return dirs
+}
+
+func uvPythonDir(home string) string {
+	if dir := os.Getenv("UV_PYTHON_INSTALL_DIR"); dir != "" {
+		return dir
+	}
+	if goruntime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "uv", "python")
+	}
+	data := os.Getenv("XDG_DATA_HOME")
+	if data == "" {
+		data = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(data, "uv", "python")
+}
+
+func pyenvVersionsDir(home string) string {
+	root := os.Getenv("PYENV_ROOT")
+	if root == "" {
+		root = filepath.Join(home, ".pyenv")
+		if goruntime.GOOS == "windows" {
+			root = filepath.Join(root, "pyenv-win")
+		}
+	}
+	return filepath.Join(root, "versions")
+}
+
+var pythonVersionPattern = regexp.MustCompile(`\d+\.\d+(\.\d+)?`)
+
+// listVersionDirs lists interpreter installations kept one per directory, as
+// uv ("cpython-3.12.4-linux-x86_64-gnu") and pyenv ("3.12.4") do.
+func listVersionDirs(dir string, envType string) []PythonEnvironment {
+	var envs []PythonEnvironment
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		env := describePythonDir(filepath.Join(dir, entry.Name()), envType+" "+entry.Name(), "")
+		if env == nil {
+			continue
+		}
+		env.Type = envType
+		env.Version = pythonVersionPattern.FindString(entry.Name())
+		envs = append(envs, *env)
+	}
+	return envs
+}
+
+// systemPythons finds the interpreters in PATH that are not part of another
+// environment.
+func (a *App) systemPythons() []PythonEnvironment {
+	names := []string{"python3", "python"}
+	for minor := 14; minor >= 8; minor-- {
+		names = append(names, fmt.Sprintf("python3.%d", minor))
+	}
+	if goruntime.GOOS == "windows" {
+		names = []string{"python.exe", "python3.exe"}
+	}
+
+	var envs []PythonEnvironment
+	seen := make(map[string]bool)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if strings.Contains(dir, "WindowsApps") || filepath.Base(dir) == "shims" {
+			continue // Microsoft Store stubs and pyenv shims
+		}
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			resolved, err := filepath.EvalSymlinks(path)
+			if err != nil || seen[resolved] {
+				continue
+			}
+			seen[resolved] = true
+			version := a.pythonInterpreterVersion(path, resolved, info)
+			prefix := filepath.Dir(dir)
+			if goruntime.GOOS == "windows" {
+				prefix = dir
+			}
+			envs = append(envs, PythonEnvironment{
+				Name:    fmt.Sprintf("%s (%s)", strings.TrimSuffix(name, ".exe"), dir),
+				Path:    prefix,
+				Type:    "system",
+				Version: version,
+				Python:  path,
+			})
+		}
+	}
+	return envs
+}
+
+// pythonInterpreterVersion runs an interpreter's --version, caching the result
+// with the tool versions until the interpreter changes.
+func (a *App) pythonInterpreterVersion(path, resolved string, info os.FileInfo) string {
+	key := "python:" + resolved
+	a.toolVersionMutex.Lock()
+	entry, ok := a.toolVersionCache[key]
+	a.toolVersionMutex.Unlock()
+	if ok && entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+		return entry.version
+	}
+	version := ""
+	if out, err := createVersionCmd(path).CombinedOutput(); err == nil {
+		version = pythonVersionPattern.FindString(string(out))
+	}
+	a.toolVersionMutex.Lock()
+	if a.toolVersionCache == nil {
+		a.toolVersionCache = make(map[string]toolVersionEntry)
+	}
+	a.toolVersionCache[key] = toolVersionEntry{modTime: info.ModTime(), size: info.Size(), version: version, checked: time.Now()}
+	a.toolVersionMutex.Unlock()
+	return version
+}
+
+// pythonActivationScript returns the bash lines that activate a Python
+// environment for a launch on Linux and macOS. Conda environments are activated
+// through conda.sh when available and virtual environments through bin/activate;
+// otherwise the environment's bin is put first in PATH with CONDA_PREFIX or
+// VIRTUAL_ENV set. Plain interpreters only get their bin put first in PATH.
+// The script then prints which python is active and warns if it is not the
+// selected one.
+func (a *App) pythonActivationScript(pythonEnv string, projectDir string) string {
+	if pythonEnv == "" || pythonEnv == "None (Default)" {
+		return ""
+	}
+	env := a.resolvePythonEnv(pythonEnv, projectDir)
+	if env == nil {
+		a.log(fmt.Sprintf("Python environment %s not found, using the default python", pythonEnv))
+		return fmt.Sprintf("echo %s\n", shellQuote("Warning: Python environment "+pythonEnv+" not found. Continuing with the default python."))
+	}
+	a.log(fmt.Sprintf("Activating %s environment %s (%s)", env.Type, env.Name, env.Path))
+	return buildPythonActivation(env, a.getCondaRoot())
+}
+
+func buildPythonActivation(env *PythonEnvironment, condaRoot string) string {
+	envPath := shellQuote(env.Path)
+	binPath := shellQuote(filepath.Join(env.Path, "bin"))
+	script := ""
+	fallback := ""
+	if env.Type == "conda" {
+		fallback = fmt.Sprintf("export CONDA_PREFIX=%s\nexport CONDA_DEFAULT_ENV=%s\nexport PATH=%s:\"$PATH\"\n",
+			envPath, shellQuote(env.Name), binPath)
+		condaSh := filepath.Join(condaRoot, "etc", "profile.d", "conda.sh")
+		if condaRoot != "" {
+			if _, err := os.Stat(condaSh); err == nil {
+				script += fmt.Sprintf("if . %s && conda activate %s; then :; else\n%sfi\n", shellQuote(condaSh), envPath, fallback)
+			}
+		}
+	} else if env.Type == "system" || env.Type == "pyenv" {
+		fallback = fmt.Sprintf("export PATH=%s:\"$PATH\"\n", binPath)
+	} else {
+		fallback = fmt.Sprintf("export VIRTUAL_ENV=%s\nexport PATH=%s:\"$PATH\"\nunset PYTHONHOME\n", envPath, binPath)
+		activate := filepath.Join(env.Path, "bin", "activate")
+		if _, err := os.Stat(activate); err == nil {
+			script += fmt.Sprintf("if . %s; then :; else\n%sfi\n", shellQuote(activate), fallback)
+		}
+	}
+	if script == "" {
+		script = fallback
+	}
+
+	script += "AICODER_PYTHON=\"$(command -v python || command -v python3)\"\n"
+	script += fmt.Sprintf("case \"$AICODER_PYTHON\" in\n  %s/*) echo \"Using Python: $AICODER_PYTHON\" ;;\n", envPath)
+	script += fmt.Sprintf("  *) echo \"Warning: python is $AICODER_PYTHON, not from \"%s ;;\nesac\n", envPath)
+	return script
+}
+
+// VerifyPythonEnvironment activates the environment the way a launch does and
+// returns the python that is then found first in PATH. It fails when that
+// python does not belong to the environment.
+func (a *App) VerifyPythonEnvironment(pythonEnv string, projectDir string) (string, error) {
+	env := a.resolvePythonEnv(pythonEnv, projectDir)
+	if env == nil {
+		return "", fmt.Errorf("python environment not found: %s", pythonEnv)
+	}
+	if goruntime.GOOS == "windows" {
+		python := venvPython(env.Path)
+		if python == "" {
+			return "", fmt.Errorf("no python.exe in %s", env.Path)
+		}
+		return python, nil
+	}
+
+	script := buildPythonActivation(env, a.getCondaRoot()) + "echo \"AICODER_PYTHON=$AICODER_PYTHON\"\n"
+	out, err := createHiddenCmd("bash", "-c", script).Output()
+	if err != nil {
+		return "", fmt.Errorf("activation failed: %v", err)
+	}
+	python := ""
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "AICODER_PYTHON=") {
+			python = strings.TrimPrefix(line, "AICODER_PYTHON=")
+		}
+	}
+	if !strings.HasPrefix(python, env.Path+string(filepath.Separator)) {
+		return python, fmt.Errorf("python resolves to %s, not to %s", python, env.Path)
+	}
+	return python, nil
+}
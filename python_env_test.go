@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakePythonEnv creates an environment directory with a python that does
+// nothing, and the venv activate script when withActivate is set.
+func fakePythonEnv(t *testing.T, withActivate bool) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "my env's")
+	bin := filepath.Join(dir, "bin")
+	if err := os.MkdirAll(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, "python"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if withActivate {
+		activate := "VIRTUAL_ENV=" + shellQuote(dir) + "\nexport VIRTUAL_ENV\nPATH=\"$VIRTUAL_ENV/bin:$PATH\"\nexport PATH\n"
+		if err := os.WriteFile(filepath.Join(bin, "activate"), []byte(activate), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestBuildPythonActivation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("activation scripts are for bash")
+	}
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	tests := []struct {
+		name         string
+		envType      string
+		withActivate bool
+	}{
+		{"venv with activate", "venv", true},
+		{"venv without activate", "venv", false},
+		{"uv", "uv", true},
+		{"conda without conda.sh", "conda", false},
+		{"pyenv", "pyenv", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := fakePythonEnv(t, tt.withActivate)
+			env := &PythonEnvironment{Name: "test", Path: dir, Type: tt.envType}
+			script := buildPythonActivation(env, "") + "command -v python\n"
+			cmd := exec.Command("bash", "-c", script)
+			cmd.Env = []string{"PATH=/usr/bin:/bin", "HOME=" + t.TempDir()}
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("activation failed: %v\n%s", err, out)
+			}
+			lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+			want := filepath.Join(dir, "bin", "python")
+			if got := lines[len(lines)-1]; got != want {
+				t.Errorf("python is %q, want %q\n%s", got, want, out)
+			}
+			if strings.Contains(string(out), "Warning") {
+				t.Errorf("activation warned:\n%s", out)
+			}
+		})
+	}
+}
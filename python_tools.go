@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// pythonTool is a tool installed with pip into its own environment under
+// ~/.cceasy/tools/py instead of with npm.
+type pythonTool struct {
+	Package string // PyPI package
+	Binary  string // Entry point linked into the tools bin directory
+	Python  string // Python version uv creates the environment with, downloading it if needed
+}
+
+// pythonTools are the Python based tools AICoder manages.
+var pythonTools = map[string]pythonTool{
+	"aider": {Package: "aider-chat", Binary: "aider", Python: "3.12"},
+}
+
+func isPythonTool(name string) bool {
+	_, ok := pythonTools[name]
+	return ok
+}
+
+// pythonToolEnvDir is the environment a Python tool is installed into.
+func (tm *ToolManager) pythonToolEnvDir(name string) string {
+	return filepath.Join(tm.toolsDir(), "py", name)
+}
+
+// pythonEnvBinDir is where pip puts the entry points of an environment.
+func pythonEnvBinDir(envDir string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(envDir, "Scripts")
+	}
+	return filepath.Join(envDir, "bin")
+}
+
+// findUv returns uv from the tools directory or the PATH, or "".
+func (tm *ToolManager) findUv() string {
+	uv := filepath.Join(toolBinDir(tm.toolsDir()), "uv")
+	if runtime.GOOS == "windows" {
+		uv += ".exe"
+	}
+	if _, err := os.Stat(uv); err == nil {
+		return uv
+	}
+	if path, err := exec.LookPath("uv"); err == nil {
+		return path
+	}
+	return ""
+}
+
+// findSystemPython returns a Python 3 interpreter from the PATH, or "".
+func findSystemPython() string {
+	for _, name := range []string{"python3", "python"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// pythonPackageVersion reads the version of a package installed in an
+// environment from its .dist-info directory.
+func pythonPackageVersion(envDir, pkg string) (string, error) {
+	prefix := strings.ReplaceAll(strings.ToLower(pkg), "-", "_") + "-"
+	patterns := []string{
+		filepath.Join(envDir, "lib", "python*", "site-packages", "*.dist-info"),
+		filepath.Join(envDir, "Lib", "site-packages", "*.dist-info"),
+	}
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, m := range matches {
+			base := strings.ToLower(filepath.Base(m))
+			if strings.HasPrefix(base, prefix) {
+				return strings.TrimSuffix(filepath.Base(m)[len(prefix):], ".dist-info"), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%s is not installed in %s", pkg, envDir)
+}
+
+// latestPythonVersion asks PyPI for the latest release of a package.
+func latestPythonVersion(pkg string) (string, error) {
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Get("https://pypi.org/pypi/" + pkg + "/json")
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch the latest %s release: %v", pkg, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch the latest %s release: %s", pkg, resp.Status)
+	}
+	var release struct {
+		Info struct {
+			Version string `json:"version"`
+		} `json:"info"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 16<<20)).Decode(&release); err != nil {
+		return "", err
+	}
+	if release.Info.Version == "" {
+		return "", fmt.Errorf("no release of %s found", pkg)
+	}
+	return release.Info.Version, nil
+}
+
+// runPythonInstallStep runs one command of a Python tool installation.
+func runPythonInstallStep(il *installLog, name string, args ...string) error {
+	if out, err := il.run(createHiddenCmd(name, args...), "install"); err != nil {
+		return il.failed(err, out)
+	}
+	return nil
+}
+
+// createPythonEnv creates a fresh environment at envDir and installs the
+// requirement into it, with uv when available and venv and pip otherwise.
+func (tm *ToolManager) createPythonEnv(tool pythonTool, envDir, requirement string, il *installLog) error {
+	if uv := tm.findUv(); uv != "" {
+		if err := runPythonInstallStep(il, uv, "venv", "--python", tool.Python, envDir); err != nil {
+			return err
+		}
+		return runPythonInstallStep(il, uv, "pip", "install", "--python", venvPython(envDir), requirement)
+	}
+	python := findSystemPython()
+	if python == "" {
+		return fmt.Errorf("neither uv nor Python 3 found. Please install uv or Python %s first", tool.Python)
+	}
+	if err := runPythonInstallStep(il, python, "-m", "venv", envDir); err != nil {
+		return err
+	}
+	return runPythonInstallStep(il, venvPython(envDir), "-m", "pip", "install", "--disable-pip-version-check", requirement)
+}
+
+// installPython installs a Python tool into its own environment and links its
+// entry point into the tools bin directory.
+func (tm *ToolManager) installPython(name string, tool pythonTool, il *installLog) error {
+	requirement := tool.Package
+	if spec := tm.versionSpec(name); spec != "latest" {
+		requirement += "==" + spec
+	}
+	envDir := tm.pythonToolEnvDir(name)
+	if err := os.MkdirAll(filepath.Dir(envDir), 0755); err != nil {
+		return fmt.Errorf("failed to create install directory: %w", err)
+	}
+
+	// Environments cannot be moved, so the new one is built in place and the
+	// current one is kept aside until the new one works
+	backup := ""
+	if _, err := os.Stat(envDir); err == nil {
+		backup = fmt.Sprintf("%s.old-%d", envDir, time.Now().UnixNano())
+		if err := os.Rename(envDir, backup); err != nil {
+			return fmt.Errorf("failed to replace the %s environment, is %s still running? %v", name, name, err)
+		}
+	}
+	installed := false
+	defer func() {
+		if installed {
+			if backup != "" {
+				os.RemoveAll(backup)
+			}
+			return
+		}
+		os.RemoveAll(envDir)
+		if backup != "" {
+			os.Rename(backup, envDir)
+		}
+	}()
+
+	if err := tm.createPythonEnv(tool, envDir, requirement, il); err != nil {
+		return err
+	}
+	version, err := pythonPackageVersion(envDir, tool.Package)
+	if err != nil {
+		return fmt.Errorf("failed to read the installed version of %s: %v", name, err)
+	}
+
+	entry := filepath.Join(pythonEnvBinDir(envDir), tool.Binary)
+	if runtime.GOOS == "windows" {
+		entry += ".exe"
+	}
+	if _, err := os.Stat(entry); err != nil {
+		return fmt.Errorf("installation completed but tool verification failed - %s not found", entry)
+	}
+	binDir := toolBinDir(tm.toolsDir())
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return err
+	}
+	if err := linkToolExecutable(binDir, entry); err != nil {
+		return err
+	}
+	installed = true
+
+	status := tm.GetToolStatus(name)
+	if !status.Installed {
+		return fmt.Errorf("installation completed but tool verification failed - %s not found", name)
+	}
+	il.log("verify", tm.app.tr("✓ %s installed and verified successfully (version: %s)", name, version))
+	return nil
+}
+
+// aiderProviderEnv maps a provider to aider's environment. Aider picks the API
+// from the prefix of the model name, so Anthropic compatible endpoints get
+// anthropic/ and everything else openai/.
+func aiderProviderEnv(model *ModelConfig, env map[string]string) {
+	id := model.ModelId
+	anthropic := strings.Contains(strings.ToLower(model.ModelUrl), "anthropic") ||
+		strings.HasPrefix(strings.ToLower(id), "claude") || strings.HasPrefix(id, "anthropic/")
+	prefix := "openai/"
+	if anthropic {
+		delete(env, "OPENAI_API_KEY")
+		delete(env, "OPENAI_API_BASE")
+		env["ANTHROPIC_API_KEY"] = model.ApiKey
+		if model.ModelUrl != "" {
+			env["ANTHROPIC_BASE_URL"] = model.ModelUrl
+		}
+		prefix = "anthropic/"
+	}
+	if id != "" && !strings.Contains(id, "/") {
+		id = prefix + id
+	}
+	if id != "" {
+		env["AIDER_MODEL"] = id
+	}
+}
+
+// remotePythonInstall returns the shell commands that install a Python tool on
+// a remote host the same way, for buildRemoteLaunchScript.
+func remotePythonInstall(name string, tool pythonTool, requirement string) string {
+	envDir := "\"$HOME/.cceasy/tools/py/" + name + "\""
+	script := "  mkdir -p \"$HOME/.cceasy/tools/py\" \"$HOME/.cceasy/tools/bin\"\n"
+	script += "  if command -v uv >/dev/null 2>&1; then\n"
+	script += fmt.Sprintf("    uv venv --python %s %s && uv pip install --python %s/bin/python %s || exit 1\n", tool.Python, envDir, envDir, shellQuote(requirement))
+	script += "  else\n"
+	script += fmt.Sprintf("    python3 -m venv %s && %s/bin/python -m pip install %s || exit 1\n", envDir, envDir, shellQuote(requirement))
+	script += "  fi\n"
+	script += fmt.Sprintf("  ln -sf %s/bin/%s \"$HOME/.cceasy/tools/bin/%s\"\n", envDir, tool.Binary, tool.Binary)
+	return script
+}
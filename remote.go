@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// localOnlyEnvVars are launch variables that only make sense on this machine.
+// They are read by the launch script and not passed on to the tool, nor
+// exported on a remote host.
+var localOnlyEnvVars = []string{exitFileEnvVar, nodeBinEnvVar}
+
+func isLocalOnlyEnvVar(k string) bool {
+	for _, v := range localOnlyEnvVars {
+		if v == k {
+			return true
+		}
+	}
+	return false
+}
+
+// sshDestination returns "user@host" (or just the host) for a project.
+func sshDestination(proj *ProjectConfig) string {
+	if proj.SSHUser != "" {
+		return proj.SSHUser + "@" + proj.SSHHost
+	}
+	return proj.SSHHost
+}
+
+// sshOptions returns the ssh options for a project. On Linux and macOS the
+// connection is shared so the script upload and the session authenticate once.
+func (a *App) sshOptions(proj *ProjectConfig) []string {
+	var opts []string
+	if proj.SSHPort != "" {
+		opts = append(opts, "-p", proj.SSHPort)
+	}
+	if proj.SSHIdentity != "" {
+		identity := proj.SSHIdentity
+		if strings.HasPrefix(identity, "~") {
+			identity = filepath.Join(a.GetUserHomeDir(), identity[1:])
+		}
+		opts = append(opts, "-i", identity, "-o", "IdentitiesOnly=yes")
+	}
+	if runtime.GOOS != "windows" {
+		controlDir := filepath.Join(a.GetUserHomeDir(), ".cceasy", "ssh")
+		if err := os.MkdirAll(controlDir, 0700); err == nil {
+			opts = append(opts, "-o", "ControlMaster=auto", "-o", "ControlPath="+filepath.Join(controlDir, "%C"), "-o", "ControlPersist=60")
+		}
+	}
+	return opts
+}
+
+// remoteShellWord quotes s for the remote shell, expanding a leading "~" to the
+// remote home directory.
+func remoteShellWord(s string) string {
+	if s == "~" {
+		return `"$HOME"`
+	}
+	if strings.HasPrefix(s, "~/") {
+		return `"$HOME"/` + shellQuote(s[2:])
+	}
+	return shellQuote(s)
+}
+
+// remoteConfigPath maps a config file of the plan to another machine: files in
+// the project go to the remote project directory, files in the home directory
+// to the remote home directory. Other files are not copied.
+func remoteConfigPath(path, home, projectDir, remoteDir string) (string, bool) {
+	if projectDir != "" {
+		if rel, err := filepath.Rel(projectDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return strings.TrimSuffix(remoteDir, "/") + "/" + filepath.ToSlash(rel), true
+		}
+	}
+	if rel, err := filepath.Rel(home, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return "~/" + filepath.ToSlash(rel), true
+	}
+	return "", false
+}
+
+// remoteMergeJSON defines a shell function that merges the JSON object on its
+// standard input into the JSON file given as argument, keeping the file's other
+// keys, with node or else python3. A file that is not valid JSON is left alone.
+// The keys come on standard input so they never show up in a process list.
+const remoteMergeJSON = `aicoder_merge_json() {
+  if command -v node >/dev/null 2>&1; then
+    node -e '
+const fs = require("fs"), f = process.argv[1];
+let c = {};
+if (fs.existsSync(f)) {
+  try { c = JSON.parse(fs.readFileSync(f, "utf8")); } catch (e) { console.error("Not updating " + f + ": " + e.message); process.exit(0); }
+}
+Object.assign(c, JSON.parse(fs.readFileSync(0, "utf8")));
+fs.writeFileSync(f + ".aicoder-tmp", JSON.stringify(c, null, 2), { mode: 0o600 });
+fs.renameSync(f + ".aicoder-tmp", f);
+' "$1"
+  elif command -v python3 >/dev/null 2>&1; then
+    python3 -c '
+import json, os, sys
+f = sys.argv[1]
+c = {}
+if os.path.exists(f):
+    try:
+        c = json.load(open(f))
+    except ValueError as e:
+        sys.stderr.write("Not updating %s: %s\n" % (f, e))
+        sys.exit(0)
+c.update(json.load(sys.stdin))
+with open(f + ".aicoder-tmp", "w") as out:
+    json.dump(c, out, indent=2)
+os.replace(f + ".aicoder-tmp", f)
+' "$1"
+  else
+    echo "Not updating $1: node or python3 is needed to merge it" >&2
+  fi
+}
+`
+
+// buildRemoteLaunchScript renders the bash script run on the remote host. It
+// deletes itself before doing anything else.
+func (a *App) buildRemoteLaunchScript(plan *launchPlan, args []string) string {
+	remoteDir := plan.project.RemotePath
+	if remoteDir == "" {
+		remoteDir = "~"
+	}
+	script := "#!/bin/bash\n"
+	script += "rm -f \"$0\"\n"
+	return script + a.buildPortableLaunchCommands(plan, args, remoteDir, plan.project.RemoteInstall, false)
+}
+
+// buildPortableLaunchCommands renders the bash commands that start a plan on
+// another machine or in a container: write the tool config files relative to
+// $HOME and workDir, export the provider environment, optionally install the
+// tool with npm and run it in workDir. Files the tool shares with AICoder only
+// get AICoder's keys merged in, so the other machine's own state is kept; with
+// emptyHome they are written with just those keys.
+func (a *App) buildPortableLaunchCommands(plan *launchPlan, args []string, workDir string, install, emptyHome bool) string {
+	script := ""
+	home := a.GetUserHomeDir()
+	merging := false
+	for _, c := range plan.configFiles {
+		path, ok := remoteConfigPath(c.Path, home, plan.projectDir, workDir)
+		if !ok {
+			a.log("Not copying config file: " + c.Path)
+			continue
+		}
+		if c.Merge != "" && emptyHome && strings.HasPrefix(path, "~/") {
+			c.Content, c.Merge = c.Merge, ""
+		}
+		if c.Merge != "" && !c.Remove && !merging {
+			script += remoteMergeJSON
+			merging = true
+		}
+		switch {
+		case c.Remove && c.IsDir:
+			script += fmt.Sprintf("rm -rf %s\n", remoteShellWord(path))
+		case c.Remove:
+			script += fmt.Sprintf("rm -f %s\n", remoteShellWord(path))
+		case c.Merge != "":
+			dir := path[:strings.LastIndex(path, "/")]
+			script += fmt.Sprintf("mkdir -p %s\n", remoteShellWord(dir))
+			script += fmt.Sprintf("printf '%%s' %s | aicoder_merge_json %s\n", shellQuote(c.Merge), remoteShellWord(path))
+		default:
+			dir := path[:strings.LastIndex(path, "/")]
+			script += fmt.Sprintf("mkdir -p %s\n", remoteShellWord(dir))
+			script += fmt.Sprintf("printf '%%s' %s > %s\n", shellQuote(c.Content), remoteShellWord(path))
+		}
+	}
+
+	env := make(map[string]string)
+	for k, v := range plan.env {
+		env[k] = v
+	}
+	for _, k := range localOnlyEnvVars {
+		delete(env, k)
+	}
+	for _, k := range providerEnvVars {
+		if _, ok := env[k]; !ok {
+			script += fmt.Sprintf("unset %s\n", k)
+		}
+	}
+	for _, k := range sortedEnvKeys(env) {
+		script += fmt.Sprintf("export %s=%s\n", k, shellQuote(env[k]))
+	}
+	script += "export PATH=\"$HOME/.cceasy/tools/bin:$PATH\"\n"
+	script += fmt.Sprintf("cd %s || exit 1\n", remoteShellWord(workDir))
+
+	if install {
+		tm := NewToolManager(a)
+		packageName := tm.GetPackageName(plan.binaryName)
+		if tool, ok := pythonTools[plan.binaryName]; ok {
+			requirement := tool.Package
+			if spec := tm.versionSpec(plan.binaryName); spec != "latest" {
+				requirement += "==" + spec
+			}
+			script += fmt.Sprintf("if ! command -v %s >/dev/null 2>&1; then\n", shellQuote(tool.Binary))
+			script += fmt.Sprintf("  echo %s\n", shellQuote("Installing "+tool.Package+"..."))
+			script += remotePythonInstall(plan.binaryName, tool, requirement)
+			script += "fi\n"
+		} else if packageName != "" {
+			npmArgs := tm.npmInstallArgs(plan.binaryName, []string{packageName + "@" + tm.versionSpec(plan.binaryName)}, "~/.cceasy/tools", "~/.cc/cache", a.npmRegistryArgs(false))
+			installLine := "npm"
+			for _, arg := range npmArgs {
+				installLine += " " + remoteShellWord(arg)
+			}
+			script += fmt.Sprintf("if ! command -v %s >/dev/null 2>&1; then\n", shellQuote(plan.binaryName))
+			script += fmt.Sprintf("  echo %s\n", shellQuote("Installing "+packageName+"..."))
+			script += "  mkdir -p \"$HOME/.cceasy/tools\" \"$HOME/.cc/cache\"\n"
+			script += "  " + installLine + " || exit 1\n"
+			script += "fi\n"
+		}
+	}
+
+	cmdLine := shellQuote(plan.binaryName)
+	for _, arg := range args {
+		cmdLine += " " + shellQuote(arg)
+	}
+	script += cmdLine + "\n"
+	return script
+}
+
+// launchRemote runs the tool of a plan on the project's SSH host in a local
+// terminal. The remote script is uploaded first, so secrets never appear on a
+// command line. exitFile, when set, receives the exit code of the session.
+func (a *App) launchRemote(plan *launchPlan, yoloMode bool, exitFile string) error {
+	proj := plan.project
+	if _, err := exec.LookPath("ssh"); err != nil {
+		return fmt.Errorf("ssh not found in PATH")
+	}
+
+	args := append(toolLaunchArgs(plan.binaryName, yoloMode, plan.model.ModelId), plan.extraArgs...)
+	stamp := time.Now().UnixNano()
+	payloadPath := filepath.Join(os.TempDir(), fmt.Sprintf("aicoder_remote_%d.sh", stamp))
+	if err := os.WriteFile(payloadPath, []byte(a.buildRemoteLaunchScript(plan, args)), 0600); err != nil {
+		return fmt.Errorf("failed to write remote launch script: %v", err)
+	}
+
+	remoteScript := fmt.Sprintf("~/.cceasy/remote/launch_%d.sh", stamp)
+	opts := a.sshOptions(proj)
+	dest := sshDestination(proj)
+	upload := terminalCommand{
+		Args:  append(append([]string{"ssh"}, opts...), dest, fmt.Sprintf("mkdir -p ~/.cceasy/remote && umask 077 && cat > %s", remoteScript)),
+		Stdin: payloadPath,
+	}
+	session := terminalCommand{Args: append(append([]string{"ssh", "-t"}, opts...), dest, "bash "+remoteScript)}
+
+	a.log(fmt.Sprintf("Launching %s on %s", plan.binaryName, dest))
+	if err := a.runInTerminal([]terminalCommand{upload, session}, []string{payloadPath}, exitFile, "AICoder - "+plan.binaryName+" @ "+proj.SSHHost); err != nil {
+		os.Remove(payloadPath)
+		return err
+	}
+	return nil
+}
+
+// TestSSHConnection connects to a project's SSH host without prompting and
+// reports the remote system and whether npm is available.
+func (a *App) TestSSHConnection(projectDir string) (string, error) {
+	config, err := a.LoadConfig()
+	if err != nil {
+		return "", err
+	}
+	proj := findProjectConfig(&config, projectDir)
+	if proj == nil || proj.SSHHost == "" {
+		return "", fmt.Errorf("no SSH host configured for %s", projectDir)
+	}
+	args := append([]string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=10"}, a.sshOptions(proj)...)
+	args = append(args, sshDestination(proj), `uname -sm; export PATH="$HOME/.cceasy/tools/bin:$PATH"; command -v npm || echo 'npm not found'`)
+	cmd := createHiddenCmd("ssh", args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return strings.TrimSpace(string(out)), fmt.Errorf("ssh %s failed: %v", sshDestination(proj), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemoteConfigPath(t *testing.T) {
+	home := filepath.Join("/home", "me")
+	project := filepath.Join(home, "src", "app")
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{filepath.Join(project, ".claude", "settings.json"), "/srv/app/.claude/settings.json", true},
+		{filepath.Join(home, ".claude.json"), "~/.claude.json", true},
+		{filepath.Join(home, ".codex", "config.toml"), "~/.codex/config.toml", true},
+		{filepath.Join(home, "src", "application", "x.json"), "~/src/application/x.json", true},
+		{filepath.Join("/etc", "hosts"), "", false},
+	}
+	for _, tt := range tests {
+		got, ok := remoteConfigPath(tt.path, home, project, "/srv/app/")
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("remoteConfigPath(%q) = %q, %v; want %q, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBuildPortableLaunchCommandsMergesSharedFiles(t *testing.T) {
+	localHome := t.TempDir()
+	a := &App{testHomeDir: localHome}
+	plan := &launchPlan{
+		binaryName: "true",
+		projectDir: filepath.Join(localHome, "project"),
+		env:        map[string]string{"ANTHROPIC_AUTH_TOKEN": "sk-it's-secret"},
+		configFiles: []ConfigFileChange{
+			{
+				Path:    filepath.Join(localHome, ".claude.json"),
+				Content: `{"oauthAccount":"local-account","customApiKeyResponses":{"approved":["secret"]}}`,
+				Merge:   `{"customApiKeyResponses":{"approved":["secret"]}}`,
+			},
+			{
+				Path:    filepath.Join(localHome, ".claude", "settings.json"),
+				Content: `{"model":"m"}`,
+			},
+		},
+	}
+
+	remoteHome := t.TempDir()
+	workDir := filepath.Join(remoteHome, "work")
+	script := a.buildPortableLaunchCommands(plan, nil, workDir, false, false)
+	if strings.Contains(script, "local-account") {
+		t.Fatalf("script ships keys AICoder does not own:\n%s", script)
+	}
+	if !strings.Contains(script, "| aicoder_merge_json \"$HOME\"/'.claude.json'") {
+		t.Fatalf("script does not merge ~/.claude.json:\n%s", script)
+	}
+	if !strings.Contains(script, "export ANTHROPIC_AUTH_TOKEN='sk-it'\\''s-secret'") {
+		t.Fatalf("script does not export the provider environment:\n%s", script)
+	}
+
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	if _, err := exec.LookPath("node"); err != nil {
+		if _, err := exec.LookPath("python3"); err != nil {
+			t.Skip("neither node nor python3 available")
+		}
+	}
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	remoteState := filepath.Join(remoteHome, ".claude.json")
+	if err := os.WriteFile(remoteState, []byte(`{"oauthAccount":"remote-account","projects":{"/x":{}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("bash", "-c", script)
+	cmd.Env = append(os.Environ(), "HOME="+remoteHome)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("script failed: %v\n%s", err, out)
+	}
+
+	data, err := os.ReadFile(remoteState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state map[string]interface{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("merged file is not JSON: %v\n%s", err, data)
+	}
+	if state["oauthAccount"] != "remote-account" || state["projects"] == nil {
+		t.Errorf("remote keys were not kept: %s", data)
+	}
+	if state["customApiKeyResponses"] == nil {
+		t.Errorf("owned keys were not merged: %s", data)
+	}
+	settings, err := os.ReadFile(filepath.Join(remoteHome, ".claude", "settings.json"))
+	if err != nil || string(settings) != `{"model":"m"}` {
+		t.Errorf("settings.json = %q, %v", settings, err)
+	}
+}
+
+func TestRemoteMergeJSONLeavesInvalidFilesAlone(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("bash", "-c", remoteMergeJSON+"printf '%s' '{\"a\":1}' | aicoder_merge_json \"$1\"", "bash", path)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("merge failed: %v\n%s", err, out)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "{not json" {
+		t.Errorf("invalid file was replaced: %q", data)
+	}
+}
+
+func TestBuildPortableLaunchCommandsEmptyHome(t *testing.T) {
+	localHome := t.TempDir()
+	a := &App{testHomeDir: localHome}
+	plan := &launchPlan{
+		binaryName: "claude",
+		projectDir: filepath.Join(localHome, "project"),
+		configFiles: []ConfigFileChange{{
+			Path:    filepath.Join(localHome, ".claude.json"),
+			Content: `{"oauthAccount":"local-account","customApiKeyResponses":{}}`,
+			Merge:   `{"customApiKeyResponses":{}}`,
+		}},
+	}
+	script := a.buildPortableLaunchCommands(plan, nil, "/workspace", true, true)
+	if strings.Contains(script, "local-account") || strings.Contains(script, "aicoder_merge_json") {
+		t.Fatalf("script does not write only the owned keys:\n%s", script)
+	}
+	if !strings.Contains(script, `printf '%s' '{"customApiKeyResponses":{}}' > "$HOME"/'.claude.json'`) {
+		t.Fatalf("script does not write ~/.claude.json:\n%s", script)
+	}
+}
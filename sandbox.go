@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	SandboxBubblewrap = "bubblewrap"
+	SandboxFirejail   = "firejail"
+)
+
+// SandboxProfile confines a tool launched on Linux with bubblewrap or firejail:
+// the project directory and the state files the tool rewrites are writable,
+// AICoder's tools and the tool's config are read-only and the rest of the home
+// directory is hidden.
+type SandboxProfile struct {
+	Type      string   `json:"type"`                 // "bubblewrap", "firejail" or empty for no sandbox
+	NoNetwork bool     `json:"no_network,omitempty"` // Cut the tool off the network
+	ReadOnly  []string `json:"read_only,omitempty"`  // Extra paths visible read-only
+	ReadWrite []string `json:"read_write,omitempty"` // Extra paths visible read-write
+}
+
+// toolConfigPaths returns the config files and directories a tool reads from
+// the home directory.
+func (a *App) toolConfigPaths(toolName string) []string {
+	home := a.GetUserHomeDir()
+	switch toolName {
+	case "claude":
+		dir, _, legacy := a.getClaudeConfigPaths()
+		return []string{dir, legacy}
+	case "gemini":
+		dir, _, legacy := a.getGeminiConfigPaths()
+		return []string{dir, legacy}
+	case "codex":
+		dir, _ := a.getCodexConfigPaths()
+		return []string{dir}
+	case "opencode":
+		dir, _ := a.getOpencodeConfigPaths()
+		return []string{dir}
+	case "iflow":
+		dir, _ := a.getIFlowConfigPaths()
+		return []string{dir}
+	case "kilo":
+		dir, _ := a.getKiloConfigPaths()
+		return []string{dir}
+	case "kode":
+		dir, _ := a.getKodeConfigPaths()
+		return []string{dir, filepath.Join(home, ".kode.json")}
+	case "codebuddy":
+		return []string{filepath.Join(home, ".codebuddy")}
+	case "qoder":
+		return []string{filepath.Join(home, ".qoder")}
+	case "aider":
+		return []string{filepath.Join(home, ".aider")}
+	}
+	return nil
+}
+
+// toolStatePaths returns the files among a tool's config paths that the tool
+// rewrites while it runs, like Claude's ~/.claude.json with its project state.
+// They are replaced by rename, so a sandbox has to bind them read-write.
+func (a *App) toolStatePaths(toolName string) []string {
+	if toolName == "claude" {
+		_, _, legacy := a.getClaudeConfigPaths()
+		return []string{legacy}
+	}
+	return nil
+}
+
+// expandHomePath expands a leading "~" of a sandbox profile path.
+func (a *App) expandHomePath(p string) string {
+	if strings.HasPrefix(p, "~") {
+		return filepath.Join(a.GetUserHomeDir(), p[1:])
+	}
+	return p
+}
+
+// sandboxWritablePaths returns the paths outside the project that a tool can
+// change in the sandbox: its state files and the profile's read-write paths.
+func (a *App) sandboxWritablePaths(profile SandboxProfile, toolName string) []string {
+	paths := append([]string{}, a.toolStatePaths(toolName)...)
+	for _, p := range profile.ReadWrite {
+		paths = append(paths, a.expandHomePath(p))
+	}
+	return paths
+}
+
+// sandboxCommand returns the command that the tool command line is appended to
+// in order to run it in the project's sandbox, or nil without a sandbox.
+// projectDir is the directory the tool works in; extraReadOnly are made visible
+// read-only in addition to the profile's paths.
+func (a *App) sandboxCommand(profile SandboxProfile, toolName, projectDir string, extraReadOnly ...string) ([]string, error) {
+	if profile.Type == "" {
+		return nil, nil
+	}
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("the %s sandbox is only supported on Linux", profile.Type)
+	}
+
+	home := a.GetUserHomeDir()
+	readOnly := []string{
+		filepath.Join(home, ".cceasy", "tools"),
+		filepath.Join(home, ".cceasy", "node"),
+	}
+	state := a.toolStatePaths(toolName)
+	for _, p := range a.toolConfigPaths(toolName) {
+		writable := false
+		for _, s := range state {
+			writable = writable || p == s
+		}
+		if !writable {
+			readOnly = append(readOnly, p)
+		}
+	}
+	readOnly = append(readOnly, extraReadOnly...)
+	for _, p := range profile.ReadOnly {
+		readOnly = append(readOnly, a.expandHomePath(p))
+	}
+	readWrite := append([]string{projectDir}, a.sandboxWritablePaths(profile, toolName)...)
+
+	switch profile.Type {
+	case SandboxBubblewrap:
+		path, err := exec.LookPath("bwrap")
+		if err != nil {
+			return nil, fmt.Errorf("bubblewrap (bwrap) not found in PATH")
+		}
+		args := []string{path, "--die-with-parent",
+			"--ro-bind", "/", "/",
+			"--dev", "/dev",
+			"--proc", "/proc",
+			"--tmpfs", "/tmp",
+			"--tmpfs", home,
+		}
+		for _, p := range readOnly {
+			args = append(args, "--ro-bind-try", p, p)
+		}
+		// Bound after the home directory is hidden, so projects inside it, like
+		// fan-out worktrees in ~/.cceasy/worktrees, stay visible and writable
+		for _, p := range readWrite {
+			args = append(args, "--bind-try", p, p)
+		}
+		if profile.NoNetwork {
+			args = append(args, "--unshare-net")
+		}
+		return append(args, "--chdir", projectDir, "--"), nil
+	case SandboxFirejail:
+		path, err := exec.LookPath("firejail")
+		if err != nil {
+			return nil, fmt.Errorf("firejail not found in PATH")
+		}
+		// Whitelisting paths in the home directory hides everything else there.
+		// Paths outside of it stay visible and cannot be whitelisted.
+		inHome := func(p string) bool {
+			rel, err := filepath.Rel(home, p)
+			return err == nil && !strings.HasPrefix(rel, "..")
+		}
+		args := []string{path, "--quiet", "--noprofile"}
+		for _, p := range readWrite {
+			if inHome(p) {
+				args = append(args, "--whitelist="+p)
+			}
+		}
+		for _, p := range readOnly {
+			if inHome(p) {
+				args = append(args, "--whitelist="+p)
+			}
+			args = append(args, "--read-only="+p)
+		}
+		if profile.NoNetwork {
+			args = append(args, "--net=none")
+		}
+		return append(args, "--"), nil
+	}
+	return nil, fmt.Errorf("unknown sandbox: %s", profile.Type)
+}
+
+// pythonEnvSandboxPaths returns what a Python environment needs to be visible in
+// a sandbox: the environment itself, the conda installation it belongs to or
+// the interpreter a virtual environment was created from.
+func (a *App) pythonEnvSandboxPaths(env *PythonEnvironment) []string {
+	paths := []string{env.Path}
+	if env.Type == "conda" {
+		if root := a.getCondaRoot(); root != "" {
+			paths = append(paths, root)
+		}
+		return paths
+	}
+	data, err := os.ReadFile(filepath.Join(env.Path, "pyvenv.cfg"))
+	if err != nil {
+		return paths
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) == 2 && strings.TrimSpace(kv[0]) == "home" {
+			// home is the bin directory of the base interpreter
+			paths = append(paths, filepath.Dir(strings.TrimSpace(kv[1])))
+		}
+	}
+	return paths
+}
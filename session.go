@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+const (
+	LaunchModeTerminal = "terminal"
+	LaunchModeTmux     = "tmux"
+	LaunchModeZellij   = "zellij"
+)
+
+// SessionInfo describes a tool running inside a terminal multiplexer session.
+type SessionInfo struct {
+	Name          string `json:"name"`
+	Multiplexer   string `json:"multiplexer"`
+	Tool          string `json:"tool"`
+	ProjectDir    string `json:"project_dir"`
+	AttachCommand string `json:"attach_command"`
+	Reused        bool   `json:"reused"` // An existing session was found and left running
+}
+
+var sessionNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// launchSessionName returns a stable session name for a tool and project, e.g.
+// "aicoder-claude-myrepo-1a2b3c". The hash keeps projects with the same
+// directory name apart.
+func launchSessionName(tool, projectDir string) string {
+	base := strings.Trim(sessionNameUnsafe.ReplaceAllString(filepath.Base(projectDir), "-"), "-")
+	if base == "" {
+		base = "project"
+	}
+	sum := sha1.Sum([]byte(filepath.Clean(projectDir)))
+	return fmt.Sprintf("aicoder-%s-%s-%s", tool, base, hex.EncodeToString(sum[:])[:6])
+}
+
+func sessionAttachCommand(multiplexer, name string) string {
+	if multiplexer == LaunchModeZellij {
+		return "zellij attach " + name
+	}
+	return "tmux attach -t " + name
+}
+
+// sessionState reports whether a session exists and, for zellij, whether it has
+// exited and only remains as a resurrectable entry.
+func sessionState(multiplexer, muxPath, name string) (exists bool, exited bool) {
+	if multiplexer == LaunchModeTmux {
+		// "=" makes tmux match the exact name instead of a prefix
+		return exec.Command(muxPath, "has-session", "-t", "="+name).Run() == nil, false
+	}
+
+	out, err := exec.Command(muxPath, "list-sessions", "--no-formatting").Output()
+	if err != nil {
+		return false, false
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == name {
+			return true, strings.Contains(line, "EXITED")
+		}
+	}
+	return false, false
+}
+
+// runningSession returns the session launchInSession would reuse for the tool
+// and project, if one is running.
+func runningSession(multiplexer, binaryName, projectDir string) (SessionInfo, bool) {
+	if runtime.GOOS == "windows" {
+		return SessionInfo{}, false
+	}
+	muxPath, err := exec.LookPath(multiplexer)
+	if err != nil {
+		return SessionInfo{}, false
+	}
+	name := launchSessionName(binaryName, projectDir)
+	if exists, exited := sessionState(multiplexer, muxPath, name); !exists || exited {
+		return SessionInfo{}, false
+	}
+	return SessionInfo{
+		Name:          name,
+		Multiplexer:   multiplexer,
+		Tool:          binaryName,
+		ProjectDir:    projectDir,
+		AttachCommand: sessionAttachCommand(multiplexer, name),
+		Reused:        true,
+	}, true
+}
+
+// launchInSession starts the tool in a detached tmux or zellij session with the
+// given environment and sandbox. If the session for this tool and project is already running
+// it is reused as is.
+func (a *App) launchInSession(multiplexer, binaryName string, yoloMode bool, pythonEnv string, projectDir string, env map[string]string, modelId string, extraArgs []string, sandbox []string) (SessionInfo, error) {
+	if runtime.GOOS == "windows" {
+		return SessionInfo{}, fmt.Errorf("%s sessions are not supported on Windows", multiplexer)
+	}
+
+	muxPath, err := exec.LookPath(multiplexer)
+	if err != nil {
+		return SessionInfo{}, fmt.Errorf("%s not found in PATH", multiplexer)
+	}
+
+	name := launchSessionName(binaryName, projectDir)
+	info := SessionInfo{
+		Name:          name,
+		Multiplexer:   multiplexer,
+		Tool:          binaryName,
+		ProjectDir:    projectDir,
+		AttachCommand: sessionAttachCommand(multiplexer, name),
+	}
+
+	exists, exited := sessionState(multiplexer, muxPath, name)
+	if exists && !exited {
+		a.log(fmt.Sprintf("Reusing running %s session %s", multiplexer, name))
+		info.Reused = true
+		return info, nil
+	}
+	if exited {
+		exec.Command(muxPath, "delete-session", name).Run()
+	}
+
+	tm := NewToolManager(a)
+	status := tm.GetToolStatus(binaryName)
+	if !status.Installed {
+		a.log(fmt.Sprintf("Tool %s not found. Attempting automatic installation...", binaryName))
+		if err := tm.InstallTool(binaryName); err != nil {
+			return info, err
+		}
+		status = tm.GetToolStatus(binaryName)
+		if !status.Installed {
+			return info, fmt.Errorf("installation completed but %s still not found", binaryName)
+		}
+	}
+
+	cmdArgs := append(toolLaunchArgs(binaryName, yoloMode, modelId), extraArgs...)
+	scriptContent := buildShellLaunchScript(projectDir, env, a.pythonActivationScript(pythonEnv, projectDir), sandbox, status.Path, cmdArgs, true)
+	scriptPath, err := a.writeLaunchScript("aicoder_session", scriptContent)
+	if err != nil {
+		return info, err
+	}
+
+	var out []byte
+	if multiplexer == LaunchModeTmux {
+		out, err = exec.Command(muxPath, "new-session", "-d", "-s", name, "-c", projectDir, scriptPath).CombinedOutput()
+	} else {
+		out, err = exec.Command(muxPath, "attach", "--create-background", name).CombinedOutput()
+		if err == nil {
+			out, err = exec.Command(muxPath, "--session", name, "run", "--name", binaryName, "--cwd", projectDir, "--", scriptPath).CombinedOutput()
+		}
+	}
+	if err != nil {
+		os.Remove(scriptPath)
+		return info, fmt.Errorf("failed to start %s session: %v\nOutput: %s", multiplexer, err, strings.TrimSpace(string(out)))
+	}
+
+	a.log(fmt.Sprintf("Started %s session %s", multiplexer, name))
+	return info, nil
+}
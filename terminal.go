@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// TerminalProfile describes how to open a terminal emulator and run a launch
+// script in it. Args is a template: {script}, {dir} and {title} are replaced
+// with the script path, the working directory and the window title.
+type TerminalProfile struct {
+	Name      string   `json:"name"`
+	Binary    string   `json:"binary"`
+	Args      []string `json:"args"`
+	Available bool     `json:"available"`
+}
+
+const (
+	TerminalProfileAuto   = "auto"
+	TerminalProfileCustom = "custom"
+)
+
+// builtinTerminals is the auto-detection order. x-terminal-emulator comes first
+// so the distribution default wins, but it is resolved to the real emulator
+// whenever the alternatives link points at one we know.
+var builtinTerminals = []TerminalProfile{
+	{Name: "x-terminal-emulator", Binary: "x-terminal-emulator", Args: []string{"-e", "{script}"}},
+	{Name: "gnome-terminal", Binary: "gnome-terminal", Args: []string{"--working-directory", "{dir}", "--", "{script}"}},
+	{Name: "konsole", Binary: "konsole", Args: []string{"--workdir", "{dir}", "-p", "tabtitle={title}", "-e", "{script}"}},
+	{Name: "xfce4-terminal", Binary: "xfce4-terminal", Args: []string{"--title", "{title}", "--working-directory", "{dir}", "-x", "{script}"}},
+	{Name: "mate-terminal", Binary: "mate-terminal", Args: []string{"--title", "{title}", "--working-directory", "{dir}", "-x", "{script}"}},
+	{Name: "tilix", Binary: "tilix", Args: []string{"--title", "{title}", "--working-directory", "{dir}", "-e", "{script}"}},
+	{Name: "terminator", Binary: "terminator", Args: []string{"--title", "{title}", "--working-directory", "{dir}", "-x", "{script}"}},
+	{Name: "alacritty", Binary: "alacritty", Args: []string{"--title", "{title}", "--working-directory", "{dir}", "-e", "{script}"}},
+	{Name: "kitty", Binary: "kitty", Args: []string{"--title", "{title}", "--directory", "{dir}", "{script}"}},
+	{Name: "wezterm", Binary: "wezterm", Args: []string{"start", "--cwd", "{dir}", "--", "{script}"}},
+	{Name: "foot", Binary: "foot", Args: []string{"--title", "{title}", "--working-directory", "{dir}", "{script}"}},
+	{Name: "ghostty", Binary: "ghostty", Args: []string{"--title={title}", "--working-directory={dir}", "-e", "{script}"}},
+	{Name: "lxterminal", Binary: "lxterminal", Args: []string{"--title={title}", "--working-directory={dir}", "-e", "{script}"}},
+	{Name: "xterm", Binary: "xterm", Args: []string{"-T", "{title}", "-e", "{script}"}},
+}
+
+func findBuiltinTerminal(name string) *TerminalProfile {
+	for i := range builtinTerminals {
+		if builtinTerminals[i].Name == name {
+			p := builtinTerminals[i]
+			return &p
+		}
+	}
+	return nil
+}
+
+// ListTerminalProfiles returns the built-in terminal table with availability
+// detected on the current system.
+func (a *App) ListTerminalProfiles() []TerminalProfile {
+	profiles := make([]TerminalProfile, len(builtinTerminals))
+	for i, p := range builtinTerminals {
+		if _, err := exec.LookPath(p.Binary); err == nil {
+			p.Available = true
+		}
+		profiles[i] = p
+	}
+	return profiles
+}
+
+// splitCommandTemplate splits a user supplied command template into arguments.
+// Single and double quotes group words and a backslash escapes the next rune,
+// which is enough for templates like `alacritty -T "{title}" -e {script}`.
+func splitCommandTemplate(tpl string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range tpl {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				args = append(args, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in terminal template")
+	}
+	if inWord {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+func expandTerminalArgs(args []string, script, dir, title string) []string {
+	r := strings.NewReplacer("{script}", script, "{dir}", dir, "{title}", title)
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = r.Replace(arg)
+	}
+	return out
+}
+
+// resolveTerminalProfile picks the terminal to use from the configured profile.
+// "auto" (or empty) honours $TERMINAL first and then walks builtinTerminals.
+func (a *App) resolveTerminalProfile(profile, template string) (*TerminalProfile, error) {
+	switch profile {
+	case TerminalProfileCustom:
+		args, err := splitCommandTemplate(template)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) == 0 {
+			return nil, fmt.Errorf("custom terminal template is empty")
+		}
+		if !strings.Contains(template, "{script}") {
+			return nil, fmt.Errorf("custom terminal template must contain {script}")
+		}
+		if _, err := exec.LookPath(args[0]); err != nil {
+			return nil, fmt.Errorf("terminal %s not found: %v", args[0], err)
+		}
+		return &TerminalProfile{Name: TerminalProfileCustom, Binary: args[0], Args: args[1:], Available: true}, nil
+	case "", TerminalProfileAuto:
+		if env := strings.TrimSpace(os.Getenv("TERMINAL")); env != "" {
+			if path, err := exec.LookPath(env); err == nil {
+				if p := findBuiltinTerminal(terminalRealName(path)); p != nil {
+					p.Binary = path
+					p.Available = true
+					return p, nil
+				}
+				return &TerminalProfile{Name: filepath.Base(path), Binary: path, Args: []string{"-e", "{script}"}, Available: true}, nil
+			}
+		}
+		for _, p := range builtinTerminals {
+			path, err := exec.LookPath(p.Binary)
+			if err != nil {
+				continue
+			}
+			// Debian alternatives link: use the real emulator's arguments when known
+			if p.Name == "x-terminal-emulator" {
+				if real := findBuiltinTerminal(terminalRealName(path)); real != nil {
+					real.Binary = path
+					real.Available = true
+					return real, nil
+				}
+			}
+			p.Binary = path
+			p.Available = true
+			return &p, nil
+		}
+		return nil, fmt.Errorf("no supported terminal emulator found")
+	default:
+		p := findBuiltinTerminal(profile)
+		if p == nil {
+			return nil, fmt.Errorf("unknown terminal profile: %s", profile)
+		}
+		path, err := exec.LookPath(p.Binary)
+		if err != nil {
+			return nil, fmt.Errorf("terminal %s not found: %v", p.Binary, err)
+		}
+		p.Binary = path
+		p.Available = true
+		return p, nil
+	}
+}
+
+// terminalRealName follows symlinks (x-terminal-emulator, $TERMINAL) and returns
+// the base name of the target, with common wrapper suffixes stripped.
+func terminalRealName(path string) string {
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		path = real
+	}
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, ".wrapper")
+	name = strings.TrimSuffix(name, ".real")
+	return name
+}
+
+// buildTerminalCommand returns the command that opens scriptPath in the
+// configured terminal emulator.
+func (a *App) buildTerminalCommand(config AppConfig, scriptPath, dir, title string) (*exec.Cmd, *TerminalProfile, error) {
+	p, err := a.resolveTerminalProfile(config.TerminalProfile, config.TerminalTemplate)
+	if err != nil {
+		return nil, nil, err
+	}
+	cmd := exec.Command(p.Binary, expandTerminalArgs(p.Args, scriptPath, dir, title)...)
+	cmd.Dir = dir
+	return cmd, p, nil
+}
+
+// TestTerminalLaunch opens the given terminal profile with a short script that
+// writes a marker file, and reports whether the script actually ran. It returns
+// the name of the terminal that was used.
+func (a *App) TestTerminalLaunch(profile string, template string) (string, error) {
+	if runtime.GOOS != "linux" {
+		return "", fmt.Errorf("terminal profiles are only supported on Linux")
+	}
+
+	p, err := a.resolveTerminalProfile(profile, template)
+	if err != nil {
+		return "", err
+	}
+
+	stamp := time.Now().UnixNano()
+	markerPath := filepath.Join(os.TempDir(), fmt.Sprintf("aicoder_termtest_%d.ok", stamp))
+	scriptPath := filepath.Join(os.TempDir(), fmt.Sprintf("aicoder_termtest_%d.sh", stamp))
+	defer os.Remove(markerPath)
+	defer os.Remove(scriptPath)
+
+	scriptContent := "#!/bin/bash\n"
+	scriptContent += "echo 'AICoder terminal test'\n"
+	scriptContent += fmt.Sprintf("touch \"%s\"\n", markerPath)
+	scriptContent += "sleep 1\n"
+	if err := os.WriteFile(scriptPath, []byte(scriptContent), 0755); err != nil {
+		return p.Name, fmt.Errorf("failed to write test script: %v", err)
+	}
+
+	dir := a.GetUserHomeDir()
+	cmd := exec.Command(p.Binary, expandTerminalArgs(p.Args, scriptPath, dir, "AICoder - terminal test")...)
+	cmd.Dir = dir
+	a.log(fmt.Sprintf("Testing terminal %s: %s %s", p.Name, cmd.Path, strings.Join(cmd.Args[1:], " ")))
+	if err := cmd.Start(); err != nil {
+		return p.Name, fmt.Errorf("failed to start %s: %v", p.Name, err)
+	}
+	go cmd.Wait()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(markerPath); err == nil {
+			a.log(fmt.Sprintf("Terminal %s works.", p.Name))
+			return p.Name, nil
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return p.Name, fmt.Errorf("terminal %s started but did not run the test script within 10 seconds", p.Name)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// UninstallTool removes a tool from ~/.cceasy/tools: its kept versions, its
+// package or Python environment and the executables linked to them. With
+// removeConfig, the tool's own config files and directories in the home
+// directory are removed as well.
+func (a *App) UninstallTool(name string, removeConfig bool) error {
+	tm := NewToolManager(a)
+	known := false
+	for _, t := range managedTools {
+		if t == name {
+			known = true
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown tool: %s", name)
+	}
+	a.log(a.tr("Uninstalling %s...", name))
+
+	tm.unlinkToolVersion(name)
+	// Executables of the installation from before versions were kept side by
+	// side, and of Python tools
+	binDir := toolBinDir(tm.toolsDir())
+	if entries, err := os.ReadDir(binDir); err == nil {
+		for _, e := range entries {
+			path := filepath.Join(binDir, e.Name())
+			if !e.IsDir() && tm.toolOwningShim(path) == name {
+				os.Remove(path)
+			}
+		}
+	}
+
+	dirs := []string{tm.toolVersionsDir(name)}
+	if isPythonTool(name) {
+		dirs = append(dirs, tm.pythonToolEnvDir(name))
+	} else if pkg := tm.GetPackageName(name); pkg != "" {
+		dirs = append(dirs, filepath.Join(npmGlobalModulesDir(tm.toolsDir()), pkg))
+	}
+	if removeConfig {
+		dirs = append(dirs, a.toolConfigPaths(name)...)
+	}
+	var firstErr error
+	for _, dir := range dirs {
+		if _, err := os.Lstat(dir); err != nil {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			a.log(fmt.Sprintf("Failed to remove %s: %v", dir, err))
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to remove %s, is %s still running? %v", dir, name, err)
+			}
+			continue
+		}
+		a.log(a.tr("Removed %s", dir))
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+
+	a.log(a.tr("%s uninstalled", name))
+	a.emitEvent("tool-uninstalled", name)
+	return nil
+}
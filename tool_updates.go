@@ -0,0 +1,155 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// maxParallelUpdates bounds the npm installs UpdateTools runs at once.
+const maxParallelUpdates = 3
+
+// OutdatedTool compares the installed version of a tool with the registry.
+type OutdatedTool struct {
+	Name      string `json:"name"`
+	Installed bool   `json:"installed"`
+	Current   string `json:"current"`
+	Latest    string `json:"latest"` // The pinned version's release for pinned tools
+	Pinned    string `json:"pinned"`
+	Outdated  bool   `json:"outdated"`
+	Error     string `json:"error"`
+}
+
+// ToolUpdateResult is the outcome of updating one tool.
+type ToolUpdateResult struct {
+	Name    string `json:"name"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Success bool   `json:"success"`
+	Error   string `json:"error"`
+}
+
+// ToolUpdateSummary is the outcome of UpdateTools.
+type ToolUpdateSummary struct {
+	Results   []ToolUpdateResult `json:"results"`
+	Succeeded []string           `json:"succeeded"`
+	Failed    []string           `json:"failed"`
+}
+
+// ToolUpdateProgress is sent as a "tool-update-progress" event.
+type ToolUpdateProgress struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // "started", "done" or "failed"
+	Error  string `json:"error,omitempty"`
+}
+
+// installedToolVersion returns the exact installed version of a tool: the
+// active kept version, the version of a Python package or of an npm package
+// installed before versions were kept side by side, and the --version output
+// otherwise.
+func (tm *ToolManager) installedToolVersion(name string, status ToolStatus) string {
+	if active := tm.loadVersionState(name).Active; active != "" {
+		return active
+	}
+	if tool, ok := pythonTools[name]; ok {
+		if version, err := pythonPackageVersion(tm.pythonToolEnvDir(name), tool.Package); err == nil {
+			return version
+		}
+	} else if pkg := tm.GetPackageName(name); pkg != "" {
+		if version, err := stagedPackageVersion(filepath.Join(npmGlobalModulesDir(tm.toolsDir()), pkg)); err == nil {
+			return version
+		}
+	}
+	return status.Version
+}
+
+// OutdatedTools checks every tool against the npm registry or its releases in
+// parallel. Pinned tools are compared with their pin.
+func (a *App) OutdatedTools() []OutdatedTool {
+	tm := NewToolManager(a)
+	npmPath := tm.getNpmPath()
+	results := make([]OutdatedTool, len(managedTools))
+	var wg sync.WaitGroup
+	for i, name := range managedTools {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			result := OutdatedTool{Name: name}
+			defer func() { results[i] = result }()
+
+			status := tm.GetToolStatus(name)
+			result.Installed = status.Installed
+			if !status.Installed {
+				return
+			}
+			result.Current = tm.installedToolVersion(name, status)
+			if spec := tm.versionSpec(name); spec != "latest" {
+				result.Pinned = spec
+			}
+			latest, err := tm.latestToolVersion(name, npmPath)
+			if err != nil {
+				result.Error = err.Error()
+				return
+			}
+			result.Latest = latest
+			result.Outdated = latest != "" && compareVersions(latest, result.Current) > 0
+		}(i, name)
+	}
+	wg.Wait()
+	return results
+}
+
+// UpdateTools updates the named tools, one after another or up to
+// maxParallelUpdates at a time. Progress is sent as "tool-update-progress"
+// events and every successful update as "tool-updated".
+func (a *App) UpdateTools(names []string, parallel bool) ToolUpdateSummary {
+	tm := NewToolManager(a)
+	results := make([]ToolUpdateResult, len(names))
+	limit := 1
+	if parallel {
+		limit = maxParallelUpdates
+	}
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = a.updateOneTool(tm, name)
+		}(i, name)
+	}
+	wg.Wait()
+
+	summary := ToolUpdateSummary{Results: results, Succeeded: []string{}, Failed: []string{}}
+	for _, r := range results {
+		if r.Success {
+			summary.Succeeded = append(summary.Succeeded, r.Name)
+		} else {
+			summary.Failed = append(summary.Failed, r.Name)
+		}
+	}
+	a.log(a.tr("Tool updates finished: %d succeeded, %d failed", len(summary.Succeeded), len(summary.Failed)))
+	return summary
+}
+
+func (a *App) updateOneTool(tm *ToolManager, name string) ToolUpdateResult {
+	result := ToolUpdateResult{Name: name}
+	status := tm.GetToolStatus(name)
+	result.From = tm.installedToolVersion(name, status)
+	a.emitEvent("tool-update-progress", ToolUpdateProgress{Name: name, Status: "started"})
+
+	if err := tm.UpdateTool(name); err != nil {
+		result.Error = err.Error()
+		a.log(a.tr("Failed to update %s: %v", name, err))
+		a.emitEvent("tool-update-progress", ToolUpdateProgress{Name: name, Status: "failed", Error: result.Error})
+		return result
+	}
+	result.Success = true
+	result.To = tm.installedToolVersion(name, tm.GetToolStatus(name))
+	a.emitEvent("tool-update-progress", ToolUpdateProgress{Name: name, Status: "done"})
+	if result.To != result.From {
+		a.emitEvent("tool-updated", name)
+	}
+	return result
+}
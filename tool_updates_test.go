@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestParseNpmViewVersion(t *testing.T) {
+	tests := []struct {
+		out  string
+		want string
+	}{
+		{`"1.2.3"`, "1.2.3"},
+		{"\"1.2.3\"\n", "1.2.3"},
+		{`["1.2.0", "1.2.10", "1.2.9"]`, "1.2.10"},
+	}
+	for _, tt := range tests {
+		got, err := parseNpmViewVersion([]byte(tt.out))
+		if err != nil || got != tt.want {
+			t.Errorf("parseNpmViewVersion(%q) = %q, %v; want %q", tt.out, got, err, tt.want)
+		}
+	}
+	for _, out := range []string{"", "[]", `{"error":{"code":"E404"}}`} {
+		if got, err := parseNpmViewVersion([]byte(out)); err == nil {
+			t.Errorf("parseNpmViewVersion(%q) = %q, want an error", out, got)
+		}
+	}
+}
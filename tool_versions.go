@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// maxToolVersions is how many installed versions of a tool are kept side by
+// side for rollback.
+const maxToolVersions = 3
+
+// ToolVersions describes the versions of a tool kept under
+// ~/.cceasy/tools/versions/<tool>.
+type ToolVersions struct {
+	Name      string   `json:"name"`
+	Active    string   `json:"active"`
+	Installed []string `json:"installed"` // Most recently active first
+	Pinned    string   `json:"pinned"`
+}
+
+// toolVersionState is the versions.json of a tool.
+type toolVersionState struct {
+	Active    string   `json:"active"`
+	Installed []string `json:"installed"`
+}
+
+func (tm *ToolManager) toolsDir() string {
+	return filepath.Join(tm.app.GetUserHomeDir(), ".cceasy", "tools")
+}
+
+// toolVersionsDir holds one install prefix per kept version of a tool.
+func (tm *ToolManager) toolVersionsDir(name string) string {
+	return filepath.Join(tm.toolsDir(), "versions", name)
+}
+
+// toolBinDir is where npm puts the executables of a prefix, and where the active
+// version's executables are linked into the tools prefix.
+func toolBinDir(prefix string) string {
+	if runtime.GOOS == "windows" {
+		return prefix
+	}
+	return filepath.Join(prefix, "bin")
+}
+
+// npmGlobalModulesDir is where "npm install -g --prefix" puts packages.
+func npmGlobalModulesDir(prefix string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(prefix, "node_modules")
+	}
+	return filepath.Join(prefix, "lib", "node_modules")
+}
+
+func (tm *ToolManager) loadVersionState(name string) toolVersionState {
+	var state toolVersionState
+	if data, err := os.ReadFile(filepath.Join(tm.toolVersionsDir(name), "versions.json")); err == nil {
+		json.Unmarshal(data, &state)
+	}
+	return state
+}
+
+func (tm *ToolManager) saveVersionState(name string, state toolVersionState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(tm.toolVersionsDir(name), "versions.json"), data, 0644)
+}
+
+// versionSpec returns the version of a tool to install: its pin, or latest.
+func (tm *ToolManager) versionSpec(name string) string {
+	config, err := tm.app.LoadConfig()
+	if err == nil {
+		if toolCfg := getToolConfig(&config, name); toolCfg != nil && toolCfg.PinnedVersion != "" {
+			return toolCfg.PinnedVersion
+		}
+	}
+	return "latest"
+}
+
+// activePackageDir returns the package directory of the active version of a
+// tool, or of the installation from before versions were kept side by side.
+func (tm *ToolManager) activePackageDir(name string) string {
+	prefix := tm.toolsDir()
+	if active := tm.loadVersionState(name).Active; active != "" {
+		prefix = filepath.Join(tm.toolVersionsDir(name), active)
+	}
+	return filepath.Join(npmGlobalModulesDir(prefix), tm.GetPackageName(name))
+}
+
+// stagedPackageVersion reads the version from an installed package.json.
+func stagedPackageVersion(pkgDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(pkgDir, "package.json"))
+	if err != nil {
+		return "", err
+	}
+	var pkg struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return "", err
+	}
+	if pkg.Version == "" || strings.ContainsAny(pkg.Version, `/\`) {
+		return "", fmt.Errorf("invalid version %q", pkg.Version)
+	}
+	return pkg.Version, nil
+}
+
+// commitToolVersion moves a finished installation from stagingDir into place,
+// makes it the active version and drops the oldest versions beyond
+// maxToolVersions.
+func (tm *ToolManager) commitToolVersion(name, stagingDir, version string) error {
+	dir := filepath.Join(tm.toolVersionsDir(name), version)
+	if _, err := os.Stat(dir); err == nil {
+		// Reinstalling a kept version
+		tm.unlinkToolVersion(name)
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to replace %s %s: %v", name, version, err)
+		}
+	}
+	if err := os.Rename(stagingDir, dir); err != nil {
+		return fmt.Errorf("failed to move %s %s into place: %v", name, version, err)
+	}
+	if err := tm.activateToolVersion(name, version); err != nil {
+		return err
+	}
+
+	// The installation from before versions were kept side by side is no longer used
+	legacyDir := filepath.Join(npmGlobalModulesDir(tm.toolsDir()), tm.GetPackageName(name))
+	if _, err := os.Stat(legacyDir); err == nil {
+		tm.app.log(tm.app.tr("Removing existing %s installation to ensure clean install...", name))
+		if err := os.RemoveAll(legacyDir); err != nil {
+			tm.app.log(tm.app.tr("Warning: Failed to completely remove old installation: %v", err))
+		}
+	}
+
+	state := tm.loadVersionState(name)
+	var kept []string
+	for i, v := range state.Installed {
+		if i < maxToolVersions {
+			kept = append(kept, v)
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(tm.toolVersionsDir(name), v)); err != nil {
+			// Probably still running; try again after the next install
+			tm.app.log(fmt.Sprintf("Failed to remove %s %s: %v", name, v, err))
+			kept = append(kept, v)
+		}
+	}
+	state.Installed = kept
+	return tm.saveVersionState(name, state)
+}
+
+// activateToolVersion links the executables of a kept version into the tools
+// prefix, where findToolPath and the launch PATH find them.
+func (tm *ToolManager) activateToolVersion(name, version string) error {
+	dir := filepath.Join(tm.toolVersionsDir(name), version)
+	entries, err := os.ReadDir(toolBinDir(dir))
+	if err != nil {
+		return fmt.Errorf("%s %s is not installed", name, version)
+	}
+	tm.unlinkToolVersion(name)
+
+	binDir := toolBinDir(tm.toolsDir())
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if runtime.GOOS == "windows" {
+			// Only npm's shims and release binaries are forwarded to
+			ext := strings.ToLower(filepath.Ext(e.Name()))
+			if ext != ".cmd" && ext != ".exe" {
+				continue
+			}
+		}
+		if err := linkToolExecutable(binDir, filepath.Join(toolBinDir(dir), e.Name())); err != nil {
+			return err
+		}
+	}
+
+	state := tm.loadVersionState(name)
+	installed := []string{version}
+	for _, v := range state.Installed {
+		if v != version {
+			installed = append(installed, v)
+		}
+	}
+	state.Active = version
+	state.Installed = installed
+	tm.app.log(fmt.Sprintf("Activated %s %s", name, version))
+	return tm.saveVersionState(name, state)
+}
+
+// linkToolExecutable makes target runnable from the tools bin directory with a
+// symlink, or on Windows, where symlinks need extra privileges, with a .cmd
+// forwarding to it.
+func linkToolExecutable(binDir, target string) error {
+	name := filepath.Base(target)
+	if runtime.GOOS == "windows" {
+		bin := strings.TrimSuffix(name, filepath.Ext(name))
+		// PowerShell and sh shims of an older installation would shadow the link
+		os.Remove(filepath.Join(binDir, bin+".ps1"))
+		os.Remove(filepath.Join(binDir, bin))
+		content := fmt.Sprintf("@echo off\r\n@\"%s\" %%*\r\n", target)
+		return os.WriteFile(filepath.Join(binDir, bin+".cmd"), []byte(content), 0644)
+	}
+	link := filepath.Join(binDir, name)
+	os.Remove(link)
+	return os.Symlink(target, link)
+}
+
+// unlinkToolVersion removes the links of a tool's active version.
+func (tm *ToolManager) unlinkToolVersion(name string) {
+	binDir := toolBinDir(tm.toolsDir())
+	entries, err := os.ReadDir(binDir)
+	if err != nil {
+		return
+	}
+	versionsDir := tm.toolVersionsDir(name) + string(filepath.Separator)
+	for _, e := range entries {
+		path := filepath.Join(binDir, e.Name())
+		var target string
+		if runtime.GOOS == "windows" {
+			if !strings.EqualFold(filepath.Ext(e.Name()), ".cmd") {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			target = string(data)
+		} else if t, err := os.Readlink(path); err == nil {
+			target = t
+		}
+		if strings.Contains(target, versionsDir) {
+			os.Remove(path)
+		}
+	}
+}
+
+// ListToolVersions returns the kept versions of a tool.
+func (a *App) ListToolVersions(name string) ToolVersions {
+	tm := NewToolManager(a)
+	state := tm.loadVersionState(name)
+	versions := ToolVersions{Name: name, Active: state.Active, Installed: state.Installed}
+	if versions.Installed == nil {
+		versions.Installed = []string{}
+	}
+	if config, err := a.LoadConfig(); err == nil {
+		if toolCfg := getToolConfig(&config, name); toolCfg != nil {
+			versions.Pinned = toolCfg.PinnedVersion
+		}
+	}
+	return versions
+}
+
+// RollbackTool switches a tool back to the version that was active before the
+// current one and pins it there, so the background update does not undo the
+// rollback. It returns the version now active.
+func (a *App) RollbackTool(name string) (string, error) {
+	tm := NewToolManager(a)
+	state := tm.loadVersionState(name)
+	if len(state.Installed) < 2 {
+		return "", fmt.Errorf("no previous version of %s to roll back to", name)
+	}
+	previous := state.Installed[1]
+	if err := tm.activateToolVersion(name, previous); err != nil {
+		return "", err
+	}
+
+	config, err := a.LoadConfig()
+	if err != nil {
+		return previous, err
+	}
+	if toolCfg := getToolConfig(&config, name); toolCfg != nil {
+		toolCfg.PinnedVersion = previous
+		if err := a.SaveConfig(config); err != nil {
+			return previous, err
+		}
+	}
+	a.log(a.tr("Rolled back %s to %s", name, previous))
+	a.emitEvent("tool-updated", name)
+	return previous, nil
+}
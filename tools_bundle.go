@@ -0,0 +1,489 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	wails_runtime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// toolsBundleFormat is the version of the bundle layout written by
+// ExportToolsBundle.
+const toolsBundleFormat = 1
+
+// ToolsBundleManifest describes an offline tools bundle. It is stored as
+// manifest.json, the last entry of the archive.
+type ToolsBundleManifest struct {
+	Format    int               `json:"format"`
+	OS        string            `json:"os"`
+	Arch      string            `json:"arch"`
+	CreatedAt string            `json:"created_at"`
+	Node      string            `json:"node"` // Bundled Node.js version, empty when none was bundled
+	Tools     []ToolsBundleTool `json:"tools"`
+	Files     map[string]string `json:"files"` // Archive path to "sha256:<hex>" or "link:<target>"
+}
+
+// ToolsBundleTool is a tool packed into a bundle.
+type ToolsBundleTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// bundleWriter writes entries relative to ~/.cceasy into a tar.gz bundle and
+// records their checksums.
+type bundleWriter struct {
+	tw       *tar.Writer
+	ccDir    string
+	manifest *ToolsBundleManifest
+}
+
+func (w *bundleWriter) add(path string, info os.FileInfo) error {
+	rel, err := filepath.Rel(w.ccDir, path)
+	if err != nil {
+		return err
+	}
+	name := filepath.ToSlash(rel)
+
+	var link string
+	if info.Mode()&os.ModeSymlink != 0 {
+		if link, err = os.Readlink(path); err != nil {
+			return err
+		}
+		// Links into ~/.cceasy must work under another home directory
+		if filepath.IsAbs(link) {
+			if r, err := filepath.Rel(w.ccDir, link); err == nil && !strings.HasPrefix(r, "..") {
+				if link, err = filepath.Rel(filepath.Dir(path), link); err != nil {
+					return err
+				}
+			}
+		}
+		link = filepath.ToSlash(link)
+	}
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	if err := w.tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	switch {
+	case info.IsDir():
+	case link != "":
+		w.manifest.Files[name] = "link:" + link
+	case info.Mode().IsRegular():
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(io.MultiWriter(w.tw, h), f); err != nil {
+			return err
+		}
+		w.manifest.Files[name] = "sha256:" + hex.EncodeToString(h.Sum(nil))
+	}
+	return nil
+}
+
+// walk adds the tree under root, leaving out paths for which skip is true.
+func (w *bundleWriter) walk(root string, skip func(path string, info os.FileInfo) bool) error {
+	if _, err := os.Lstat(root); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if skip != nil && skip(path, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return w.add(path, info)
+	})
+}
+
+// toolOwningShim returns the managed tool an executable in the tools bin
+// directory belongs to: a link (or a Windows shim) into the tool's kept
+// versions, its package or its Python environment. It returns "" for the
+// Node.js runtime's own files.
+func (tm *ToolManager) toolOwningShim(path string) string {
+	var target string
+	if runtime.GOOS == "windows" {
+		if info, err := os.Stat(path); err != nil || info.Size() > 64*1024 {
+			return ""
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return ""
+		}
+		target = string(data)
+	} else if t, err := os.Readlink(path); err == nil {
+		target = t
+	}
+	target = filepath.ToSlash(target)
+	for _, name := range managedTools {
+		versions := filepath.ToSlash(tm.toolVersionsDir(name)) + "/"
+		if isPythonTool(name) {
+			if strings.Contains(target, filepath.ToSlash(tm.pythonToolEnvDir(name))+"/") {
+				return name
+			}
+			continue
+		}
+		pkg := "node_modules/" + tm.GetPackageName(name) + "/"
+		if strings.Contains(target, versions) || strings.Contains(target, pkg) {
+			return name
+		}
+	}
+	return ""
+}
+
+// ExportToolsBundle packs the Node.js runtime and the given tools from
+// ~/.cceasy/tools, plus the skills storage, into a tar.gz archive for
+// ImportToolsBundle on a machine without network access. Only the active
+// version of each tool is packed. An empty path writes the bundle to
+// ~/.cceasy/bundles. It returns the path of the bundle.
+func (a *App) ExportToolsBundle(tools []string, path string) (string, error) {
+	tm := NewToolManager(a)
+	ccDir := filepath.Join(a.GetUserHomeDir(), ".cceasy")
+	toolsDir := tm.toolsDir()
+
+	manifest := ToolsBundleManifest{
+		Format:    toolsBundleFormat,
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		CreatedAt: time.Now().Format(time.RFC3339),
+		Tools:     []ToolsBundleTool{},
+		Files:     make(map[string]string),
+	}
+	selected := make(map[string]bool)
+	versioned := make(map[string]string)
+	for _, name := range tools {
+		if isPythonTool(name) {
+			// Virtual environments refer to their interpreter by absolute path
+			return "", fmt.Errorf("%s is a Python tool and cannot be bundled", name)
+		}
+		status := tm.GetToolStatus(name)
+		if !status.Installed {
+			return "", fmt.Errorf("%s is not installed", name)
+		}
+		selected[name] = true
+		if active := tm.loadVersionState(name).Active; active != "" {
+			versioned[name] = active
+		}
+		manifest.Tools = append(manifest.Tools, ToolsBundleTool{Name: name, Version: tm.installedToolVersion(name, status)})
+	}
+
+	nodeExe := filepath.Join(toolBinDir(toolsDir), "node")
+	if runtime.GOOS == "windows" {
+		nodeExe += ".exe"
+	}
+	if out, err := outputWithTimeout(createVersionCmd(nodeExe), toolVersionTimeout); err == nil {
+		manifest.Node = strings.TrimPrefix(strings.TrimSpace(string(out)), "v")
+	} else {
+		a.log(a.tr("Warning: no local Node.js runtime in %s, the bundle needs Node.js on the target machine", toolsDir))
+	}
+
+	if path == "" {
+		path = filepath.Join(ccDir, "bundles", fmt.Sprintf("aicoder-tools-%s-%s-%s.tar.gz", runtime.GOOS, runtime.GOARCH, time.Now().Format("20060102-150405")))
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	gz := gzip.NewWriter(f)
+	w := &bundleWriter{tw: tar.NewWriter(gz), ccDir: ccDir, manifest: &manifest}
+
+	err = func() error {
+		// The runtime and tools installed before versions were kept side by side.
+		// Executables of kept versions are linked again by the import.
+		binDir := toolBinDir(toolsDir)
+		modulesDir := npmGlobalModulesDir(toolsDir)
+		err := w.walk(toolsDir, func(p string, info os.FileInfo) bool {
+			if p == filepath.Join(toolsDir, "versions") || p == filepath.Join(toolsDir, "py") {
+				return true
+			}
+			if filepath.Dir(p) == modulesDir || filepath.Dir(filepath.Dir(p)) == modulesDir {
+				for _, name := range managedTools {
+					if p == filepath.Join(modulesDir, tm.GetPackageName(name)) {
+						return !selected[name] || versioned[name] != ""
+					}
+				}
+			}
+			if filepath.Dir(p) == binDir && !info.IsDir() {
+				if owner := tm.toolOwningShim(p); owner != "" {
+					return !selected[owner] || versioned[owner] != ""
+				}
+			}
+			return false
+		})
+		if err != nil {
+			return err
+		}
+		for _, name := range tools {
+			if version := versioned[name]; version != "" {
+				if err := w.walk(filepath.Join(tm.toolVersionsDir(name), version), nil); err != nil {
+					return err
+				}
+			}
+		}
+		if err := w.walk(a.GetSkillsDir(""), nil); err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{Name: "manifest.json", Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}
+		if err := w.tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := w.tw.Write(data); err != nil {
+			return err
+		}
+		if err := w.tw.Close(); err != nil {
+			return err
+		}
+		return gz.Close()
+	}()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write tools bundle: %v", err)
+	}
+	a.log(a.tr("Tools bundle written to %s (%d files)", path, len(manifest.Files)))
+	return path, nil
+}
+
+// ImportToolsBundle unpacks a bundle written by ExportToolsBundle into
+// ~/.cceasy. Every file is checked against the manifest before anything is
+// moved into place, and the bundle must match this machine's OS and arch.
+func (a *App) ImportToolsBundle(path string) (ToolsBundleManifest, error) {
+	var manifest ToolsBundleManifest
+	tm := NewToolManager(a)
+	ccDir := filepath.Join(a.GetUserHomeDir(), ".cceasy")
+	stagingDir := filepath.Join(ccDir, fmt.Sprintf(".import-%d", time.Now().UnixNano()))
+	if err := os.MkdirAll(stagingDir, 0755); err != nil {
+		return manifest, err
+	}
+	defer os.RemoveAll(stagingDir)
+
+	found, manifestData, err := extractToolsBundle(path, stagingDir)
+	if err != nil {
+		return manifest, fmt.Errorf("failed to read tools bundle: %v", err)
+	}
+	if manifestData == nil {
+		return manifest, fmt.Errorf("not a tools bundle: manifest.json is missing")
+	}
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return manifest, fmt.Errorf("invalid bundle manifest: %v", err)
+	}
+	if manifest.Format != toolsBundleFormat {
+		return manifest, fmt.Errorf("unsupported bundle format %d", manifest.Format)
+	}
+	if manifest.OS != runtime.GOOS || manifest.Arch != runtime.GOARCH {
+		return manifest, fmt.Errorf("the bundle is for %s/%s, this machine is %s/%s", manifest.OS, manifest.Arch, runtime.GOOS, runtime.GOARCH)
+	}
+	for name, sum := range manifest.Files {
+		if found[name] != sum {
+			return manifest, fmt.Errorf("checksum mismatch for %s", name)
+		}
+	}
+	for name := range found {
+		if _, ok := manifest.Files[name]; !ok {
+			return manifest, fmt.Errorf("%s is not listed in the manifest", name)
+		}
+	}
+
+	// Verified; move everything into place
+	err = filepath.Walk(stagingDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(stagingDir, p)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(ccDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		if existing, err := os.Lstat(dest); err == nil && !existing.IsDir() {
+			os.Remove(dest)
+		}
+		return os.Rename(p, dest)
+	})
+	if err != nil {
+		return manifest, fmt.Errorf("failed to install tools bundle: %v", err)
+	}
+
+	for _, tool := range manifest.Tools {
+		if _, err := os.Stat(filepath.Join(tm.toolVersionsDir(tool.Name), tool.Version)); err == nil {
+			if err := tm.activateToolVersion(tool.Name, tool.Version); err != nil {
+				return manifest, err
+			}
+		}
+		a.emitEvent("tool-installed", tool.Name)
+	}
+	a.log(a.tr("Tools bundle imported from %s", path))
+	return manifest, nil
+}
+
+// throughSymlink reports whether a path below dir has a symlink among its
+// parent directories, so writing to it could end up outside dir.
+func throughSymlink(dir, target string) bool {
+	rel, err := filepath.Rel(dir, filepath.Dir(target))
+	if err != nil || rel == "." {
+		return err != nil
+	}
+	p := dir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		p = filepath.Join(p, part)
+		info, err := os.Lstat(p)
+		if err != nil {
+			return false // Not created yet
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// extractToolsBundle unpacks a bundle into dir. It returns the checksums of
+// the unpacked entries, in the manifest's format, and the manifest itself.
+// Links are created after every file is written, and must resolve to a path
+// inside dir, so no entry can be written through a link.
+func extractToolsBundle(path, dir string) (map[string]string, []byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer gz.Close()
+
+	found := make(map[string]string)
+	var manifest []byte
+	var links []*tar.Header
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		name := strings.TrimSuffix(header.Name, "/")
+		if name == "manifest.json" {
+			if manifest, err = io.ReadAll(io.LimitReader(tr, 64<<20)); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		clean := filepath.Clean(filepath.FromSlash(name))
+		if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return nil, nil, fmt.Errorf("illegal path in bundle: %s", header.Name)
+		}
+		target := filepath.Join(dir, clean)
+		if throughSymlink(dir, target) {
+			return nil, nil, fmt.Errorf("illegal path in bundle: %s", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return nil, nil, err
+			}
+		case tar.TypeSymlink:
+			if filepath.IsAbs(filepath.FromSlash(header.Linkname)) {
+				return nil, nil, fmt.Errorf("illegal link in bundle: %s -> %s", header.Name, header.Linkname)
+			}
+			links = append(links, header)
+			found[name] = "link:" + header.Linkname
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return nil, nil, err
+			}
+			out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(header.Mode)&0777)
+			if err != nil {
+				return nil, nil, err
+			}
+			h := sha256.New()
+			_, err = io.Copy(io.MultiWriter(out, h), tr)
+			out.Close()
+			if err != nil {
+				return nil, nil, err
+			}
+			found[name] = "sha256:" + hex.EncodeToString(h.Sum(nil))
+		default:
+			return nil, nil, fmt.Errorf("unsupported entry in bundle: %s", header.Name)
+		}
+	}
+
+	for _, header := range links {
+		target := filepath.Join(dir, filepath.Clean(filepath.FromSlash(strings.TrimSuffix(header.Name, "/"))))
+		if throughSymlink(dir, target) {
+			return nil, nil, fmt.Errorf("illegal path in bundle: %s", header.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, nil, err
+		}
+		if err := os.Symlink(filepath.FromSlash(header.Linkname), target); err != nil {
+			return nil, nil, err
+		}
+	}
+	// Links must stay inside the bundle, also when they point through each other
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, header := range links {
+		target := filepath.Join(dir, filepath.Clean(filepath.FromSlash(strings.TrimSuffix(header.Name, "/"))))
+		resolved, err := filepath.EvalSymlinks(target)
+		if err != nil || !strings.HasPrefix(resolved, realDir+string(filepath.Separator)) {
+			return nil, nil, fmt.Errorf("illegal link in bundle: %s -> %s", header.Name, header.Linkname)
+		}
+	}
+	return found, manifest, nil
+}
+
+// SelectToolsBundleFile lets the user pick a bundle for ImportToolsBundle.
+func (a *App) SelectToolsBundleFile() string {
+	selection, err := wails_runtime.OpenFileDialog(a.ctx, wails_runtime.OpenDialogOptions{
+		Title: "Select Tools Bundle",
+		Filters: []wails_runtime.FileFilter{
+			{DisplayName: "Tools Bundles", Pattern: "*.tar.gz"},
+		},
+	})
+	if err != nil {
+		return ""
+	}
+	return selection
+}
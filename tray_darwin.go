@@ -279,6 +279,12 @@ func setupTray(app *App, appOptions *options.App) {
 				mShow.SetTitle(t["show"])
 				mLaunch.SetTitle(t["launch"])
 				mQuit.SetTitle(t["quit"])
+				if cfg, err := app.LoadConfig(); err == nil {
+					proxied := app.trayProxiedProvider(&cfg)
+					for key, item := range modelItems {
+						item.SetTitle(app.trayProviderTitle(key, proxied))
+					}
+				}
 			}
 
 			// Register config change listener
@@ -286,6 +292,7 @@ func setupTray(app *App, appOptions *options.App) {
 				if modelItems == nil {
 					return
 				}
+				proxied := app.trayProxiedProvider(&cfg)
 				for name, item := range modelItems {
 					// Only check the currently active tool's current model
 					if (cfg.ActiveTool == "claude" && name == "claude-"+cfg.Claude.CurrentModel) ||
@@ -301,6 +308,7 @@ func setupTray(app *App, appOptions *options.App) {
 					} else {
 						item.Uncheck()
 					}
+					item.SetTitle(app.trayProviderTitle(name, proxied))
 				}
 				runtime.EventsEmit(app.ctx, "config-changed", cfg)
 			}
@@ -314,7 +322,8 @@ func setupTray(app *App, appOptions *options.App) {
 				go func() {
 					currentConfig, _ := app.LoadConfig()
 					path := app.GetCurrentProjectPath()
-					app.LaunchTool(currentConfig.ActiveTool, false, false, false, "", path, false)
+					proj := findProjectConfig(&currentConfig, path)
+					app.LaunchTool(currentConfig.ActiveTool, false, false, false, "", path, proj != nil && proj.UseProxy)
 				}()
 			})
 
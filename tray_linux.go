@@ -265,6 +265,12 @@ func setupTray(app *App, appOptions *options.App) {
 					mShow.SetTitle(t["show"])
 					mLaunch.SetTitle(t["launch"])
 					mQuit.SetTitle(t["quit"])
+					if cfg, err := app.LoadConfig(); err == nil {
+						proxied := app.trayProxiedProvider(&cfg)
+						for key, item := range toolItems {
+							item.SetTitle(app.trayProviderTitle(key, proxied))
+						}
+					}
 				}
 
 				// Register config change listener
@@ -272,6 +278,7 @@ func setupTray(app *App, appOptions *options.App) {
 					if toolItems == nil {
 						return
 					}
+					proxied := app.trayProxiedProvider(&cfg)
 					for key, item := range toolItems {
 						// Only check the currently active tool's current model
 						if (cfg.ActiveTool == "claude" && key == "claude-"+cfg.Claude.CurrentModel) ||
@@ -287,6 +294,7 @@ func setupTray(app *App, appOptions *options.App) {
 						} else {
 							item.Uncheck()
 						}
+						item.SetTitle(app.trayProviderTitle(key, proxied))
 					}
 					runtime.EventsEmit(app.ctx, "config-changed", cfg)
 				}
@@ -300,7 +308,8 @@ func setupTray(app *App, appOptions *options.App) {
 					go func() {
 						currentConfig, _ := app.LoadConfig()
 						path := app.GetCurrentProjectPath()
-						app.LaunchTool(currentConfig.ActiveTool, false, false, false, "", path, false)
+						proj := findProjectConfig(&currentConfig, path)
+						app.LaunchTool(currentConfig.ActiveTool, false, false, false, "", path, proj != nil && proj.UseProxy)
 					}()
 				})
 				mQuit.Click(func() {
@@ -297,6 +297,12 @@ func setupTray(app *App, appOptions *options.App) {
 								mLaunch.SetTitle(t["launch"])
 
 												mQuit.SetTitle(t["quit"])
+												if cfg, err := app.LoadConfig(); err == nil {
+													proxied := app.trayProxiedProvider(&cfg)
+													for key, item := range toolItems {
+														item.SetTitle(app.trayProviderTitle(key, proxied))
+													}
+												}
 
 											}
 
@@ -316,6 +322,7 @@ func setupTray(app *App, appOptions *options.App) {
 												if toolItems == nil {
 													return
 												}
+												proxied := app.trayProxiedProvider(&cfg)
 												for name, item := range toolItems {
 													// Only check the currently active tool's current model
 													if (cfg.ActiveTool == "claude" && name == "claude-"+cfg.Claude.CurrentModel) ||
@@ -331,6 +338,7 @@ func setupTray(app *App, appOptions *options.App) {
 													} else {
 														item.Uncheck()
 													}
+													item.SetTitle(app.trayProviderTitle(name, proxied))
 												}
 												runtime.EventsEmit(app.ctx, "config-changed", cfg)
 											}
@@ -372,7 +380,8 @@ func setupTray(app *App, appOptions *options.App) {
 				go func() {
 					currentConfig, _ := app.LoadConfig()
 					path := app.GetCurrentProjectPath()
-					app.LaunchTool(currentConfig.ActiveTool, false, false, false, "", path, false)
+					proj := findProjectConfig(&currentConfig, path)
+					app.LaunchTool(currentConfig.ActiveTool, false, false, false, "", path, proj != nil && proj.UseProxy)
 				}()
 			})
 